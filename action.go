@@ -8,14 +8,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
+	"mime"
 	nethttp "net/http"
-	"reflect"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
-	gdtcontext "github.com/gdt-dev/core/context"
 	"github.com/gdt-dev/core/debug"
+	"gopkg.in/yaml.v3"
 )
 
 // Action describes the the HTTP-specific action that is performed by the test.
@@ -26,6 +29,35 @@ type Action struct {
 	Method string `yaml:"method,omitempty"`
 	// Data is the payload to send along in request
 	Data interface{} `yaml:"data,omitempty"`
+	// Body describes a payload to send along in the request that is encoded
+	// as raw text, a form or multipart form instead of JSON.
+	Body *Body `yaml:"body,omitempty"`
+	// DataFile is the path to a JSON or YAML file containing the payload to
+	// send along in the request. The file's contents are treated exactly like
+	// the Data field. Relative paths are resolved relative to the test
+	// scenario file.
+	DataFile string `yaml:"data_file,omitempty"`
+	// BodyFile is the path to a file whose contents are sent verbatim as the
+	// payload of the request. Relative paths are resolved relative to the
+	// test scenario file.
+	BodyFile string `yaml:"body_file,omitempty"`
+	// Headers is a map, keyed by HTTP header name, of values to send along in
+	// the request. A header may have multiple values.
+	Headers map[string][]string `yaml:"headers,omitempty"`
+	// Query is a map, keyed by query parameter name, of values to add to the
+	// URL's query string. A query parameter may have multiple values.
+	Query map[string][]string `yaml:"query,omitempty"`
+	// Cookies is a map, keyed by cookie name, of cookies to send along in the
+	// request in addition to any cookies in the scenario's cookie jar.
+	Cookies map[string]string `yaml:"cookies,omitempty"`
+	// Auth describes the credentials to send along in the request. If not
+	// specified, any credentials in the scenario's `http` defaults are used.
+	Auth *Auth `yaml:"auth,omitempty"`
+	// FollowRedirects is whether, or how many, HTTP redirects are followed.
+	// It may be a bool or the maximum number of redirects to follow. If not
+	// specified, the `follow_redirects` in the scenario's `http` defaults is
+	// used and, failing that, the HTTP client's redirect policy.
+	FollowRedirects *RedirectPolicy `yaml:"follow_redirects,omitempty"`
 	// Shortcut for URL and Method of "GET"
 	Get string `yaml:"get,omitempty"`
 	// Shortcut for URL and Method of "POST"
@@ -36,6 +68,10 @@ type Action struct {
 	Patch string `yaml:"patch,omitempty"`
 	// Shortcut for URL and Method of "DELETE"
 	Delete string `yaml:"delete,omitempty"`
+	// Shortcut for URL and Method of "HEAD"
+	Head string `yaml:"head,omitempty"`
+	// Shortcut for URL and Method of "OPTIONS"
+	Options string `yaml:"options,omitempty"`
 }
 
 // Do performs a single HTTP request, returning the HTTP Response and any
@@ -44,48 +80,186 @@ func (a *Action) Do(
 	ctx context.Context,
 	c *nethttp.Client,
 	defaults *Defaults,
+) (*nethttp.Response, error) {
+	return a.do(ctx, c, defaults, &Timings{})
+}
+
+// do is like Do but records the timings of the HTTP request in the supplied
+// Timings. If the HTTP request is sent more than once, the timings are those
+// of the last HTTP request.
+func (a *Action) do(
+	ctx context.Context,
+	c *nethttp.Client,
+	defaults *Defaults,
+	timings *Timings,
 ) (*nethttp.Response, error) {
 	url, err := a.getURL(ctx, defaults)
 	if err != nil {
 		return nil, err
 	}
 
-	debug.Printf(ctx, "http: > %s %s", a.Method, url)
-	var reqData io.Reader
-	if a.Data != nil {
-		if err := a.processRequestData(ctx); err != nil {
-			return nil, err
-		}
-		jsonBody, err := json.Marshal(a.Data)
-		if err != nil {
-			return nil, err
-		}
-		b := bytes.NewReader(jsonBody)
-		if b.Size() > 0 {
-			sendData, _ := io.ReadAll(b)
-			debug.Printf(ctx, "http: > %s", sendData)
-			b.Seek(0, 0) // nolint:errcheck
-		}
-		reqData = b
+	auth := a.authFor(defaults)
+	c = withRedirectPolicy(c, a.redirectPolicyFor(defaults))
+	url, maskedURL, err := auth.withQuery(ctx, url)
+	if err != nil {
+		return nil, err
 	}
 
-	req, err := nethttp.NewRequest(a.Method, url, reqData)
+	debug.Printf(ctx, "http: > %s %s", a.Method, maskedURL)
+	payload, contentType, err := a.requestBody(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if len(payload) > 0 && a.payloadPrintable() {
+		debug.Printf(ctx, "http: > %s", payload)
+	}
 
-	resp, err := c.Do(req)
+	resp, err := a.send(
+		ctx, c, defaults, auth, timings, url, payload, contentType,
+	)
 	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode == nethttp.StatusUnauthorized && auth.refresh() {
+		debug.Printf(ctx, "http: < %d (refreshing credentials)", resp.StatusCode)
+		io.Copy(io.Discard, resp.Body) // nolint:errcheck
+		resp.Body.Close()              // nolint:errcheck
+		resp, err = a.send(
+			ctx, c, defaults, auth, timings, url, payload, contentType,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
 	debug.Printf(ctx, "http: < %d", resp.StatusCode)
 	return resp, err
 }
 
+// send builds and sends a single HTTP request with the supplied payload,
+// returning the HTTP Response. The timings of the HTTP request are recorded in
+// the supplied Timings.
+func (a *Action) send(
+	ctx context.Context,
+	c *nethttp.Client,
+	defaults *Defaults,
+	auth *Auth,
+	timings *Timings,
+	url string,
+	payload []byte,
+	contentType string,
+) (*nethttp.Response, error) {
+	var reqData io.Reader
+	if payload != nil {
+		reqData = bytes.NewReader(payload)
+	}
+	// The request's context is only used for tracing the timings of the
+	// request. Timeouts are handled by the gdt scenario runner.
+	req, err := nethttp.NewRequestWithContext(
+		timings.withTrace(context.Background()), a.Method, url, reqData,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.setHeaders(ctx, req); err != nil {
+		return nil, err
+	}
+	if err := a.setCookies(ctx, req); err != nil {
+		return nil, err
+	}
+	if err := auth.setHeaders(ctx, defaults, req); err != nil {
+		return nil, err
+	}
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return c.Do(req)
+}
+
+// payloadFields returns the names of the fields describing the request payload
+// that have been set. Only one of these fields may be set.
+func (a *Action) payloadFields() []string {
+	fields := []string{}
+	if a.Data != nil {
+		fields = append(fields, "data")
+	}
+	if a.Body != nil {
+		fields = append(fields, "body")
+	}
+	if a.DataFile != "" {
+		fields = append(fields, "data_file")
+	}
+	if a.BodyFile != "" {
+		fields = append(fields, "body_file")
+	}
+	return fields
+}
+
+// payloadPrintable returns true if the request payload is textual and can be
+// written to the debug output.
+func (a *Action) payloadPrintable() bool {
+	if a.BodyFile != "" {
+		return false
+	}
+	return a.Body == nil || a.Body.Mode != BodyModeMultipart
+}
+
+// requestBody returns the encoded payload to send in the HTTP request along
+// with the Content-Type of the encoded payload. If the test has no payload, the
+// returned payload is nil.
+func (a *Action) requestBody(ctx context.Context) ([]byte, string, error) {
+	switch {
+	case a.Data != nil:
+		data, err := a.processRequestData(ctx, a.Data)
+		if err != nil {
+			return nil, "", err
+		}
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return nil, "", err
+		}
+		return payload, "application/json", nil
+	case a.Body != nil:
+		return a.Body.encode(ctx, a)
+	case a.DataFile != "":
+		contents, err := os.ReadFile(a.DataFile)
+		if err != nil {
+			return nil, "", err
+		}
+		// YAML is a superset of JSON so we can decode either.
+		var data interface{}
+		if err := yaml.Unmarshal(contents, &data); err != nil {
+			return nil, "", err
+		}
+		data, err = a.processRequestData(ctx, data)
+		if err != nil {
+			return nil, "", err
+		}
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return nil, "", err
+		}
+		return payload, "application/json", nil
+	case a.BodyFile != "":
+		payload, err := os.ReadFile(a.BodyFile)
+		if err != nil {
+			return nil, "", err
+		}
+		contentType := mime.TypeByExtension(filepath.Ext(a.BodyFile))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		return payload, contentType, nil
+	}
+	return nil, "", nil
+}
+
 // getURL returns the URL to use for the test's HTTP request. The test's url
 // field is first queried to see if it is the special $LOCATION string. If it
 // is, then we return the previous HTTP response's Location header. Otherwise,
-// we construct the URL from the httpFile's base URL and the test's url field.
+// we construct the URL from the httpFile's base URL and the test's url field,
+// after interpolating any variable references in the url field. Any query
+// parameters in the test's query field are then merged with any query string
+// already present in the URL.
 func (a *Action) getURL(
 	ctx context.Context,
 	defaults *Defaults,
@@ -99,114 +273,138 @@ func (a *Action) getURL(
 		if err != nil {
 			return "", ErrExpectedLocationHeader
 		}
-		return url.String(), nil
+		return a.withQuery(ctx, url)
+	}
+	path, err := expandString(ctx, a.URL)
+	if err != nil {
+		return "", err
 	}
 	base := defaults.BaseURLFromContext(ctx)
-	return base + a.URL, nil
+	url, err := neturl.Parse(base + path)
+	if err != nil {
+		return "", err
+	}
+	return a.withQuery(ctx, url)
 }
 
-// processRequestData looks through the raw data interface{} that was
-// unmarshaled during parse for any string values that look like JSONPath
-// expressions. If we find any, we query the fixture registry to see if any
-// fixtures have a value that matches the JSONPath expression. See
-// gdt.fixtures:jsonFixture for more information on how this works
-func (a *Action) processRequestData(ctx context.Context) error {
-	if a.Data == nil {
-		return nil
-	}
-	// Get a pointer to the unmarshaled interface{} so we can mutate the
-	// contents pointed to
-	p := reflect.ValueOf(&a.Data)
-
-	// We're interested in the value pointed to by the interface{}, which is
-	// why we do a double Elem() here.
-	v := p.Elem().Elem()
-	vt := v.Type()
-
-	switch vt.Kind() {
-	case reflect.Slice:
-		for i := 0; i < v.Len(); i++ {
-			item := v.Index(i).Elem()
-			it := item.Type()
-			err := a.preprocessMap(ctx, item, it.Key(), it.Elem())
+// withQuery returns the string form of the supplied URL after merging the
+// test's query parameters into the URL's query string. Query parameter values
+// are expanded in the same way as values in the request data are. See
+// `Action.processRequestData`.
+func (a *Action) withQuery(
+	ctx context.Context,
+	url *neturl.URL,
+) (string, error) {
+	if len(a.Query) == 0 {
+		return url.String(), nil
+	}
+	q, err := neturl.ParseQuery(url.RawQuery)
+	if err != nil {
+		return "", err
+	}
+	for key, vals := range a.Query {
+		for _, val := range vals {
+			val, err = expandString(ctx, val)
+			if err != nil {
+				return "", err
+			}
+			q.Add(key, val)
+		}
+	}
+	url.RawQuery = q.Encode()
+	return url.String(), nil
+}
+
+// setHeaders sets the HTTP headers on the supplied request. Header values are
+// expanded in the same way as values in the request data are. See
+// `Action.processRequestData`. Credentials in the headers are masked in the
+// debug output. See `maskHeader`.
+func (a *Action) setHeaders(ctx context.Context, req *nethttp.Request) error {
+	keys := make([]string, 0, len(a.Headers))
+	for key := range a.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, val := range a.Headers[key] {
+			val, err := expandString(ctx, val)
 			if err != nil {
 				return err
 			}
+			debug.Printf(ctx, "http: > %s: %s", key, maskHeader(key, val))
+			if nethttp.CanonicalHeaderKey(key) == "Host" {
+				// net/http ignores the Host header in favour of the
+				// Request.Host field.
+				req.Host = val
+				continue
+			}
+			req.Header.Add(key, val)
 		}
-	case reflect.Map:
-		err := a.preprocessMap(ctx, v, vt.Key(), vt.Elem())
+	}
+	return nil
+}
+
+// setCookies adds the test's cookies to the supplied request. Cookie values
+// are expanded in the same way as values in the request data are. See
+// `Action.processRequestData`. Cookie values are masked in the debug output.
+func (a *Action) setCookies(ctx context.Context, req *nethttp.Request) error {
+	names := make([]string, 0, len(a.Cookies))
+	for name := range a.Cookies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		val, err := expandString(ctx, a.Cookies[name])
 		if err != nil {
 			return err
 		}
+		debug.Printf(ctx, "http: > Cookie: %s=%s", name, maskedSecret)
+		req.AddCookie(&nethttp.Cookie{Name: name, Value: val})
 	}
 	return nil
 }
 
-// processRequestDataMap processes a map pointed to by v, transforming any
-// string keys or values of the map into the results of calling the fixture
-// set's State() method.
-func (a *Action) preprocessMap(
+// processRequestData returns a copy of the supplied raw data interface{} that
+// was unmarshaled during parse with all string keys and values expanded. If a
+// string looks like a JSONPath expression, we query the fixture registry to
+// see if any fixtures have a value that matches the JSONPath expression. See
+// gdt.fixtures:jsonFixture for more information on how this works. Otherwise,
+// any `$(name)` variable references in the string are interpolated. See
+// `expandValue`.
+//
+// The supplied data is not modified, which ensures that variable references
+// are interpolated afresh each time the test spec is evaluated.
+func (a *Action) processRequestData(
 	ctx context.Context,
-	m reflect.Value,
-	kt reflect.Type,
-	vt reflect.Type,
-) error {
-	it := m.MapRange()
-	for it.Next() {
-		if kt.Kind() == reflect.String {
-			keyStr := it.Key().String()
-			fixtures := gdtcontext.Fixtures(ctx)
-			for _, f := range fixtures {
-				if !f.HasState(keyStr) {
-					continue
-				}
-				trKeyStr := f.State(keyStr)
-				keyStr = trKeyStr.(string)
+	data any,
+) (any, error) {
+	switch data := data.(type) {
+	case map[string]any:
+		res := make(map[string]any, len(data))
+		for key, val := range data {
+			key, err := expandString(ctx, key)
+			if err != nil {
+				return nil, err
 			}
-
-			val := it.Value()
-			err := a.preprocessMapValue(ctx, m, reflect.ValueOf(keyStr), val, val.Type())
+			val, err := a.processRequestData(ctx, val)
 			if err != nil {
-				return err
+				return nil, err
 			}
+			res[key] = val
 		}
-	}
-	return nil
-}
-
-func (a *Action) preprocessMapValue(
-	ctx context.Context,
-	m reflect.Value,
-	k reflect.Value,
-	v reflect.Value,
-	vt reflect.Type,
-) error {
-	if vt.Kind() == reflect.Interface {
-		v = v.Elem()
-		vt = v.Type()
-	}
-
-	switch vt.Kind() {
-	case reflect.Slice:
-		for i := 0; i < v.Len(); i++ {
-			item := v.Index(i)
-			fmt.Println(item)
-		}
-		fmt.Printf("map element is an array.\n")
-	case reflect.Map:
-		return a.preprocessMap(ctx, v, vt.Key(), vt.Elem())
-	case reflect.String:
-		valStr := v.String()
-		fixtures := gdtcontext.Fixtures(ctx)
-		for _, f := range fixtures {
-			if !f.HasState(valStr) {
-				continue
+		return res, nil
+	case []any:
+		res := make([]any, len(data))
+		for x, item := range data {
+			item, err := a.processRequestData(ctx, item)
+			if err != nil {
+				return nil, err
 			}
-			trValStr := f.State(valStr)
-			m.SetMapIndex(k, reflect.ValueOf(trValStr))
+			res[x] = item
 		}
-	default:
-		return nil
+		return res, nil
+	case string:
+		return expandValue(ctx, data)
 	}
-	return nil
+	return data, nil
 }
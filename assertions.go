@@ -7,6 +7,8 @@ package http
 import (
 	"context"
 	nethttp "net/http"
+	"os"
+	"regexp"
 	"strings"
 
 	"github.com/gdt-dev/core/api"
@@ -17,36 +19,108 @@ import (
 type Expect struct {
 	// JSON contains the assertions about JSON data in the response
 	JSON *gdtjson.Expect `yaml:"json,omitempty"`
-	// Headers contains a list of HTTP headers that should be in the response
-	Headers []string `yaml:"headers,omitempty"`
+	// XML contains the assertions about XML data in the response
+	XML *XMLExpect `yaml:"xml,omitempty"`
+	// HTML contains a list of assertions about the elements of an HTML
+	// response. Each assertion may be a string containing a CSS selector that
+	// should select at least one element or an object describing the
+	// assertion.
+	HTML []*HTMLExpect `yaml:"html,omitempty"`
+	// JWT contains assertions about a JSON Web Token in the response
+	JWT *JWTExpect `yaml:"jwt,omitempty"`
+	// Redirects contains a list of assertions, one for each redirect
+	// followed to arrive at the response, about the HTTP status code and
+	// Location HTTP header of the redirect. An empty list asserts that no
+	// redirects were followed.
+	Redirects []*RedirectExpect `yaml:"redirects,omitempty"`
+	// Headers contains a list of assertions about HTTP headers in the
+	// response. Each assertion may be a string containing the name of an
+	// HTTP header that should be in the response, a string of the form
+	// `Key: Value` or an object describing the assertion.
+	Headers []*HeaderExpect `yaml:"headers,omitempty"`
+	// Cookies contains a list of assertions about cookies set by the
+	// response. Each assertion may be a string containing the name of a
+	// cookie that should be set or an object describing the assertion.
+	Cookies []*CookieExpect `yaml:"cookies,omitempty"`
+	// Format is the format of the response content that the JSON assertions
+	// are made against, one of "json", "yaml" or "ndjson". By default, the
+	// format is determined by the Content-Type HTTP header of the response.
+	Format string `yaml:"format,omitempty"`
 	// Strings contains a list of strings that should be present in the
 	// response content
 	Strings []string `yaml:"strings,omitempty"`
-	// Status contains the numeric HTTP status code (e.g. 200 or 404) that
-	// should be returned in the HTTP response
-	Status *int `yaml:"status,omitempty"`
+	// Body contains the assertions about the content of the response
+	Body *BodyExpect `yaml:"body,omitempty"`
+	// Snapshot contains an assertion that the content of the response equals
+	// a previously recorded snapshot
+	Snapshot *SnapshotExpect `yaml:"snapshot,omitempty"`
+	// TLS contains assertions about the TLS connection the response was
+	// received on and the certificate presented by the server
+	TLS *TLSExpect `yaml:"tls,omitempty"`
+	// Latency contains assertions about the time spent in each phase of the
+	// HTTP request, e.g. `total: <200ms`
+	Latency *LatencyExpect `yaml:"latency,omitempty"`
+	// Status describes the HTTP status code that should be returned in the
+	// HTTP response. It may be a numeric HTTP status code (e.g. 200 or 404),
+	// a status class (e.g. 2xx), a range of status codes (e.g. 200-299), a
+	// list of any of these or a map with a `not` field containing any of
+	// these.
+	Status *Status `yaml:"status,omitempty"`
 }
 
-// headerEqual returns true if the supplied http.Response contains an expected
-// HTTP header
-func headerEqual(r *nethttp.Response, exp string) bool {
-	key, val, wantVal := strings.Cut(exp, ":")
-	_, ok := r.Header[key]
-	if !ok {
-		return false
+// BodyExpect contains assertions about the content of an HTTP response.
+type BodyExpect struct {
+	// Contains is a list of strings that should be present in the response
+	// content.
+	Contains []string `yaml:"contains,omitempty"`
+	// NotContains is a list of strings that should not be present in the
+	// response content.
+	NotContains []string `yaml:"not_contains,omitempty"`
+	// Matches is a list of regular expressions that the response content
+	// should match.
+	Matches []string `yaml:"matches,omitempty"`
+	// Equals is the exact expected response content.
+	Equals *string `yaml:"equals,omitempty"`
+	// EqualsFile is the path to a file containing the exact expected
+	// response content. Relative paths are resolved relative to the test
+	// scenario file.
+	EqualsFile string `yaml:"equals_file,omitempty"`
+	// matches contains the compiled Matches regular expressions.
+	matches []*regexp.Regexp
+}
+
+// check returns nil if the supplied response content satisfies the
+// assertions, otherwise it returns the first failure.
+func (e *BodyExpect) check(b []byte) error {
+	body := string(b)
+	for _, s := range e.Contains {
+		if !strings.Contains(body, s) {
+			return HTTPNotInBody(s)
+		}
 	}
-	if !wantVal {
-		// We were only looking for the Header key, didn't care about value.
-		return true
+	for _, s := range e.NotContains {
+		if strings.Contains(body, s) {
+			return HTTPInBody(s)
+		}
+	}
+	for x, re := range e.matches {
+		if !re.MatchString(body) {
+			return HTTPBodyNotMatched(e.Matches[x])
+		}
 	}
-	valGot := r.Header.Get(key)
-	if valGot == "" {
-		return false
+	if e.Equals != nil && *e.Equals != body {
+		return HTTPBodyNotEqual("expected", *e.Equals, body)
 	}
-	// If the string being compared is of the form Key: Value,
-	// then we check for both existence and the value of the
-	// header
-	return strings.EqualFold(val, valGot)
+	if e.EqualsFile != "" {
+		exp, err := os.ReadFile(e.EqualsFile)
+		if err != nil {
+			return err
+		}
+		if string(exp) != body {
+			return HTTPBodyNotEqual(e.EqualsFile, string(exp), body)
+		}
+	}
+	return nil
 }
 
 // assertions contains all assertions made for the exec test
@@ -59,6 +133,10 @@ type assertions struct {
 	r *nethttp.Response
 	// b is the body of the `nethttp.Response` we've read into a buffer
 	b []byte
+	// t contains the timings of the HTTP request
+	t *Timings
+	// jwtKey is the key the signature of the JWT is verified with, if any
+	jwtKey any
 }
 
 // Fail appends a supplied error to the set of failed assertions
@@ -86,19 +164,71 @@ func (a *assertions) OK(
 	res := true
 	if exp.Status != nil {
 		got := a.r.StatusCode
-		if *exp.Status != got {
-			a.Fail(HTTPStatusNotEqual(*exp.Status, got))
+		if !exp.Status.Matches(got) {
+			a.Fail(HTTPStatusNotEqual(exp.Status, got))
+			return false
+		}
+	}
+	if exp.Redirects != nil {
+		if err := checkRedirects(ctx, exp.Redirects, a.r); err != nil {
+			a.Fail(err)
 			return false
 		}
 	}
 	if exp.JSON != nil {
-		ja := gdtjson.New(exp.JSON, a.b)
+		format := responseFormat(exp, a.r)
+		b, err := jsonBody(format, a.b)
+		if err != nil {
+			a.Fail(HTTPBodyNotDecoded(format, err))
+			return false
+		}
+		// The path formats are checked here instead of by gdtjson so that
+		// formats registered with RegisterFormat are honoured.
+		jexp := *exp.JSON
+		jexp.PathFormats = nil
+		if jexp.Schema != "" {
+			addSchemaFormats()
+		}
+		ja := gdtjson.New(&jexp, b)
 		if !ja.OK(ctx) {
 			for _, f := range ja.Failures() {
 				a.Fail(f)
 			}
 			return false
 		}
+		if err := checkPathFormats(exp.JSON.PathFormats, b); err != nil {
+			a.Fail(err)
+			return false
+		}
+	}
+
+	if exp.XML != nil {
+		if err := exp.XML.check(a.b); err != nil {
+			a.Fail(err)
+			return false
+		}
+	}
+
+	if len(exp.HTML) > 0 {
+		doc, err := parseHTML(a.b)
+		if err != nil {
+			a.Fail(err)
+			return false
+		}
+		for _, html := range exp.HTML {
+			if err := html.check(doc); err != nil {
+				a.Fail(err)
+				return false
+			}
+		}
+	}
+
+	if exp.JWT != nil {
+		format := responseFormat(exp, a.r)
+		if err := exp.JWT.check(ctx, a.r, a.b, format, a.jwtKey); err != nil {
+			a.Fail(err)
+			return false
+		}
 	}
 
 	if len(exp.Strings) > 0 {
@@ -110,14 +240,51 @@ func (a *assertions) OK(
 		}
 	}
 
+	if exp.Body != nil {
+		if err := exp.Body.check(a.b); err != nil {
+			a.Fail(err)
+			return false
+		}
+	}
+
 	if len(exp.Headers) > 0 {
 		for _, header := range exp.Headers {
-			if !headerEqual(a.r, header) {
-				a.Fail(HTTPHeaderNotIn(header, a.r.Header))
+			if err := header.check(a.r.Header); err != nil {
+				a.Fail(err)
 				return false
 			}
 		}
 	}
+
+	if len(exp.Cookies) > 0 {
+		for _, cookie := range exp.Cookies {
+			if err := cookie.check(a.r); err != nil {
+				a.Fail(err)
+				return false
+			}
+		}
+	}
+
+	if exp.TLS != nil {
+		if err := exp.TLS.check(a.r.TLS); err != nil {
+			a.Fail(err)
+			return false
+		}
+	}
+
+	if exp.Latency != nil {
+		if err := exp.Latency.check(a.t); err != nil {
+			a.Fail(err)
+			return false
+		}
+	}
+
+	if exp.Snapshot != nil {
+		if err := exp.Snapshot.check(ctx, a.b); err != nil {
+			a.Fail(err)
+			return false
+		}
+	}
 	return res
 }
 
@@ -127,11 +294,15 @@ func newAssertions(
 	exp *Expect,
 	r *nethttp.Response,
 	b []byte,
+	t *Timings,
+	jwtKey any,
 ) api.Assertions {
 	return &assertions{
 		failures: []error{},
 		exp:      exp,
 		r:        r,
 		b:        b,
+		t:        t,
+		jwtKey:   jwtKey,
 	}
 }
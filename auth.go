@@ -0,0 +1,230 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	nethttp "net/http"
+	neturl "net/url"
+	"strings"
+
+	"github.com/gdt-dev/core/debug"
+)
+
+const (
+	// AuthTypeBasic indicates HTTP Basic authentication.
+	AuthTypeBasic = "basic"
+	// AuthTypeBearer indicates a bearer token sent in the Authorization
+	// header.
+	AuthTypeBearer = "bearer"
+	// AuthTypeAPIKey indicates an API key sent in an HTTP header or query
+	// parameter.
+	AuthTypeAPIKey = "api_key"
+	// AuthTypeOAuth2 indicates a bearer token obtained using the OAuth2
+	// client credentials grant.
+	AuthTypeOAuth2 = "oauth2"
+)
+
+var validAuthTypes = []string{
+	AuthTypeBasic,
+	AuthTypeBearer,
+	AuthTypeAPIKey,
+	AuthTypeOAuth2,
+}
+
+// maskedSecret replaces credentials in debug output.
+const maskedSecret = "REDACTED"
+
+// secretHeaderParts are the lowercase substrings of the names of HTTP headers,
+// such as `X-API-Key` or `X-Auth-Token`, whose values are credentials.
+var secretHeaderParts = []string{
+	"api-key", "apikey", "token", "secret", "password",
+}
+
+// maskHeader returns the value of the named HTTP request header as it is
+// written to the debug output. The values of the Authorization,
+// Proxy-Authorization and Cookie headers and of headers that look like they
+// contain credentials are masked. The authentication scheme of an
+// Authorization header and the names of cookies are kept.
+func maskHeader(key string, val string) string {
+	switch nethttp.CanonicalHeaderKey(key) {
+	case "Authorization", "Proxy-Authorization":
+		if scheme, _, found := strings.Cut(val, " "); found {
+			return scheme + " " + maskedSecret
+		}
+		return maskedSecret
+	case "Cookie":
+		cookies := strings.Split(val, ";")
+		for x, c := range cookies {
+			name, _, _ := strings.Cut(strings.TrimSpace(c), "=")
+			cookies[x] = name + "=" + maskedSecret
+		}
+		return strings.Join(cookies, "; ")
+	}
+	lower := strings.ToLower(key)
+	for _, part := range secretHeaderParts {
+		if strings.Contains(lower, part) {
+			return maskedSecret
+		}
+	}
+	return val
+}
+
+// Auth describes the credentials sent along in the HTTP request. Exactly one
+// of the `basic`, `bearer`, `api_key` or `oauth2` fields may be specified.
+//
+// Credential values are expanded in the same way as values in the request
+// data are, which means they may be a fixture state key or may reference
+// variables saved by prior test specs. Environment variables are expanded
+// when the test scenario is parsed. Credentials are never written to the
+// debug output.
+type Auth struct {
+	// Type is the type of authentication, set from whichever of the `basic`,
+	// `bearer`, `api_key` or `oauth2` fields was specified.
+	Type string `yaml:"-"`
+	// Basic contains the username and password for HTTP Basic
+	// authentication.
+	Basic *BasicAuth `yaml:"basic,omitempty"`
+	// Bearer is a token sent in the Authorization header as
+	// `Bearer <token>`.
+	Bearer string `yaml:"bearer,omitempty"`
+	// APIKey is an API key sent in an HTTP header or query parameter.
+	APIKey *APIKeyAuth `yaml:"api_key,omitempty"`
+	// OAuth2 describes how to obtain a bearer token using the OAuth2 client
+	// credentials grant.
+	OAuth2 *OAuth2Auth `yaml:"oauth2,omitempty"`
+}
+
+// BasicAuth contains the credentials for HTTP Basic authentication.
+type BasicAuth struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password,omitempty"`
+}
+
+// APIKeyAuth describes an API key sent in the HTTP request. Exactly one of
+// Header or Query should be set.
+type APIKeyAuth struct {
+	// Header is the name of the HTTP header the API key is sent in.
+	Header string `yaml:"header,omitempty"`
+	// Query is the name of the query parameter the API key is sent in.
+	Query string `yaml:"query,omitempty"`
+	// Value is the API key.
+	Value string `yaml:"value"`
+}
+
+// withQuery returns the supplied URL with any API key sent in a query
+// parameter added to the URL's query string, along with a copy of the URL
+// that has the API key masked and is suitable for the debug output.
+func (a *Auth) withQuery(
+	ctx context.Context,
+	url string,
+) (string, string, error) {
+	if a == nil || a.Type != AuthTypeAPIKey || a.APIKey.Query == "" {
+		return url, url, nil
+	}
+	key, err := expandString(ctx, a.APIKey.Value)
+	if err != nil {
+		return "", "", err
+	}
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return "", "", err
+	}
+	q := u.Query()
+	q.Set(a.APIKey.Query, maskedSecret)
+	u.RawQuery = q.Encode()
+	masked := u.String()
+	q.Set(a.APIKey.Query, key)
+	u.RawQuery = q.Encode()
+	return u.String(), masked, nil
+}
+
+// setHeaders sets any HTTP headers carrying the credentials on the supplied
+// request. The supplied defaults are used to request an OAuth2 access token
+// when needed.
+func (a *Auth) setHeaders(
+	ctx context.Context,
+	defaults *Defaults,
+	req *nethttp.Request,
+) error {
+	if a == nil {
+		return nil
+	}
+	switch a.Type {
+	case AuthTypeBasic:
+		username, err := expandString(ctx, a.Basic.Username)
+		if err != nil {
+			return err
+		}
+		password, err := expandString(ctx, a.Basic.Password)
+		if err != nil {
+			return err
+		}
+		creds := base64.StdEncoding.EncodeToString(
+			[]byte(username + ":" + password),
+		)
+		setAuthHeader(ctx, req, "Authorization", "Basic ", creds)
+	case AuthTypeBearer:
+		token, err := expandString(ctx, a.Bearer)
+		if err != nil {
+			return err
+		}
+		setAuthHeader(ctx, req, "Authorization", "Bearer ", token)
+	case AuthTypeAPIKey:
+		if a.APIKey.Header == "" {
+			return nil
+		}
+		key, err := expandString(ctx, a.APIKey.Value)
+		if err != nil {
+			return err
+		}
+		setAuthHeader(ctx, req, a.APIKey.Header, "", key)
+	case AuthTypeOAuth2:
+		token, err := a.OAuth2.accessToken(ctx, defaults)
+		if err != nil {
+			return err
+		}
+		setAuthHeader(ctx, req, "Authorization", "Bearer ", token)
+	}
+	return nil
+}
+
+// refresh discards any cached credentials that may be refreshed, returning
+// true if the request should be retried with refreshed credentials.
+func (a *Auth) refresh() bool {
+	if a == nil || a.Type != AuthTypeOAuth2 {
+		return false
+	}
+	a.OAuth2.invalidate()
+	return true
+}
+
+// setAuthHeader sets the named HTTP header on the supplied request to the
+// supplied prefix followed by the supplied secret. The secret is masked in
+// the debug output.
+func setAuthHeader(
+	ctx context.Context,
+	req *nethttp.Request,
+	key string,
+	prefix string,
+	secret string,
+) {
+	req.Header.Set(key, prefix+secret)
+	debug.Printf(ctx, "http: > %s: %s%s", key, prefix, maskedSecret)
+}
+
+// authFor returns the credentials to use for the HTTP request. Credentials
+// specified in the test spec take precedence over any specified in the
+// scenario's defaults.
+func (a *Action) authFor(defaults *Defaults) *Auth {
+	if a.Auth != nil {
+		return a.Auth
+	}
+	if defaults != nil {
+		return defaults.Auth
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// BodyModeJSON indicates the request payload is encoded as JSON.
+	BodyModeJSON = "json"
+	// BodyModeRaw indicates the request payload is sent verbatim.
+	BodyModeRaw = "raw"
+	// BodyModeForm indicates the request payload is encoded as
+	// application/x-www-form-urlencoded.
+	BodyModeForm = "form"
+	// BodyModeMultipart indicates the request payload is encoded as
+	// multipart/form-data.
+	BodyModeMultipart = "multipart"
+)
+
+var validBodyModes = []string{
+	BodyModeJSON,
+	BodyModeRaw,
+	BodyModeForm,
+	BodyModeMultipart,
+}
+
+// Body describes how the payload of the HTTP request is encoded. Exactly one
+// of the `json`, `raw`, `form` or `multipart` fields may be specified, and
+// which one is specified selects the encoding of the payload.
+type Body struct {
+	// Mode is the encoding of the payload, set from whichever of the `json`,
+	// `raw`, `form` or `multipart` fields was specified.
+	Mode string `yaml:"-"`
+	// JSON is a payload that will be encoded as JSON. Elements of the payload
+	// may be JSONPath expressions, exactly like the `data` field.
+	JSON any `yaml:"json,omitempty"`
+	// Raw is a string that will be sent verbatim as the payload.
+	Raw string `yaml:"raw,omitempty"`
+	// Form is a map, keyed by form field name, of values that will be
+	// encoded as application/x-www-form-urlencoded.
+	Form map[string][]string `yaml:"form,omitempty"`
+	// Multipart is a list of parts that will be encoded as
+	// multipart/form-data.
+	Multipart []*Part `yaml:"multipart,omitempty"`
+	// ContentType overrides the Content-Type HTTP header that is otherwise
+	// determined by the encoding of the payload.
+	ContentType string `yaml:"content_type,omitempty"`
+}
+
+// Part is a single field of a multipart/form-data payload. Either Value or
+// File should be set.
+type Part struct {
+	// Name is the form field name of the part.
+	Name string `yaml:"name"`
+	// Value is the string contents of the part.
+	Value string `yaml:"value,omitempty"`
+	// File is the path to a file whose contents will be sent as the part.
+	// Relative paths are resolved relative to the test scenario file.
+	File string `yaml:"file,omitempty"`
+	// Filename is the filename sent for a File part. Defaults to the base
+	// name of File.
+	Filename string `yaml:"filename,omitempty"`
+	// ContentType is the Content-Type of the part.
+	ContentType string `yaml:"content_type,omitempty"`
+}
+
+// encode returns the encoded payload along with the Content-Type of the
+// encoded payload.
+func (b *Body) encode(
+	ctx context.Context,
+	a *Action,
+) ([]byte, string, error) {
+	var payload []byte
+	var contentType string
+	var err error
+	switch b.Mode {
+	case BodyModeJSON:
+		data, err := a.processRequestData(ctx, b.JSON)
+		if err != nil {
+			return nil, "", err
+		}
+		payload, err = json.Marshal(data)
+		if err != nil {
+			return nil, "", err
+		}
+		contentType = "application/json"
+	case BodyModeForm:
+		form := neturl.Values{}
+		for key, vals := range b.Form {
+			for _, val := range vals {
+				val, err = expandString(ctx, val)
+				if err != nil {
+					return nil, "", err
+				}
+				form.Add(key, val)
+			}
+		}
+		payload = []byte(form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	case BodyModeMultipart:
+		payload, contentType, err = b.encodeMultipart(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+	default:
+		raw, err := interpolate(ctx, b.Raw)
+		if err != nil {
+			return nil, "", err
+		}
+		payload = []byte(raw)
+		contentType = "text/plain; charset=utf-8"
+	}
+	if b.ContentType != "" {
+		contentType = b.ContentType
+	}
+	return payload, contentType, nil
+}
+
+// encodeMultipart returns the multipart/form-data encoded payload along with
+// the Content-Type, including the multipart boundary.
+func (b *Body) encodeMultipart(
+	ctx context.Context,
+) ([]byte, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, p := range b.Multipart {
+		value, err := expandString(ctx, p.Value)
+		if err != nil {
+			return nil, "", err
+		}
+		if p.File == "" && p.ContentType == "" {
+			if err := w.WriteField(p.Name, value); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+		h := make(textproto.MIMEHeader)
+		contentType := p.ContentType
+		disposition := `form-data; name="` + escapeQuotes(p.Name) + `"`
+		if p.File != "" {
+			filename := p.Filename
+			if filename == "" {
+				filename = filepath.Base(p.File)
+			}
+			disposition += `; filename="` + escapeQuotes(filename) + `"`
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+		}
+		h.Set("Content-Disposition", disposition)
+		h.Set("Content-Type", contentType)
+		pw, err := w.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		if p.File == "" {
+			if _, err := io.WriteString(pw, value); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+		f, err := os.Open(p.File)
+		if err != nil {
+			return nil, "", err
+		}
+		_, err = io.Copy(pw, f)
+		f.Close() // nolint:errcheck
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// escapeQuotes escapes backslashes and double quotes in a Content-Disposition
+// parameter value, exactly like mime/multipart does.
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
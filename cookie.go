@@ -0,0 +1,176 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+package http
+
+import (
+	"fmt"
+	nethttp "net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SameSiteStrict indicates a cookie with the `SameSite=Strict` attribute.
+	SameSiteStrict = "strict"
+	// SameSiteLax indicates a cookie with the `SameSite=Lax` attribute.
+	SameSiteLax = "lax"
+	// SameSiteNone indicates a cookie with the `SameSite=None` attribute.
+	SameSiteNone = "none"
+)
+
+var validSameSites = []string{
+	SameSiteStrict,
+	SameSiteLax,
+	SameSiteNone,
+}
+
+// CookieExpect describes an assertion about a cookie set by the response in a
+// `Set-Cookie` HTTP header. With only a Name, the assertion is that the cookie
+// is set.
+type CookieExpect struct {
+	// Name is the name of the cookie.
+	Name string `yaml:"name"`
+	// Value is the expected value of the cookie.
+	Value *string `yaml:"value,omitempty"`
+	// Matches is a regular expression the value of the cookie must match.
+	Matches string `yaml:"matches,omitempty"`
+	// Absent, if true, asserts that the response does not set the cookie.
+	Absent bool `yaml:"absent,omitempty"`
+	// Secure asserts whether the cookie has the Secure attribute.
+	Secure *bool `yaml:"secure,omitempty"`
+	// HTTPOnly asserts whether the cookie has the HttpOnly attribute.
+	HTTPOnly *bool `yaml:"http_only,omitempty"`
+	// SameSite is the expected SameSite attribute of the cookie, one of
+	// "strict", "lax" or "none".
+	SameSite string `yaml:"same_site,omitempty"`
+	// Path is the expected Path attribute of the cookie.
+	Path *string `yaml:"path,omitempty"`
+	// Domain is the expected Domain attribute of the cookie. A leading dot is
+	// ignored.
+	Domain *string `yaml:"domain,omitempty"`
+	// Session, if true, asserts that the cookie has neither an Expires nor a
+	// Max-Age attribute. If false, asserts that it has one of them.
+	Session *bool `yaml:"session,omitempty"`
+	// Expires is a condition on the time until the cookie expires, e.g.
+	// `>=24h`. A cookie with a zero or negative Max-Age or an Expires
+	// attribute in the past expires in zero time.
+	Expires *DurationCondition `yaml:"expires,omitempty"`
+	// re is the compiled Matches regular expression.
+	re *regexp.Regexp
+}
+
+// check returns nil if the cookies set by the supplied HTTP response satisfy
+// the assertion, otherwise it returns the failure.
+func (e *CookieExpect) check(r *nethttp.Response) error {
+	var cookie *nethttp.Cookie
+	for _, c := range r.Cookies() {
+		if c.Name == e.Name {
+			cookie = c
+		}
+	}
+	if e.Absent {
+		if cookie != nil {
+			return HTTPCookiePresent(e.Name)
+		}
+		return nil
+	}
+	if cookie == nil {
+		return HTTPCookieNotIn(e.Name)
+	}
+	if e.Value != nil && cookie.Value != *e.Value {
+		return HTTPCookieNotMatched(
+			e.Name, fmt.Sprintf("value %q", *e.Value), cookie.Value,
+		)
+	}
+	if e.re != nil && !e.re.MatchString(cookie.Value) {
+		return HTTPCookieNotMatched(
+			e.Name, fmt.Sprintf("value matching %q", e.Matches), cookie.Value,
+		)
+	}
+	if e.Secure != nil && cookie.Secure != *e.Secure {
+		return HTTPCookieNotMatched(
+			e.Name, fmt.Sprintf("Secure %t", *e.Secure),
+			strconv.FormatBool(cookie.Secure),
+		)
+	}
+	if e.HTTPOnly != nil && cookie.HttpOnly != *e.HTTPOnly {
+		return HTTPCookieNotMatched(
+			e.Name, fmt.Sprintf("HttpOnly %t", *e.HTTPOnly),
+			strconv.FormatBool(cookie.HttpOnly),
+		)
+	}
+	if e.SameSite != "" {
+		if got := sameSiteString(cookie.SameSite); got != e.SameSite {
+			return HTTPCookieNotMatched(
+				e.Name, fmt.Sprintf("SameSite %s", e.SameSite), got,
+			)
+		}
+	}
+	if e.Path != nil && cookie.Path != *e.Path {
+		return HTTPCookieNotMatched(
+			e.Name, fmt.Sprintf("Path %q", *e.Path), cookie.Path,
+		)
+	}
+	if e.Domain != nil {
+		exp := strings.TrimPrefix(*e.Domain, ".")
+		got := strings.TrimPrefix(cookie.Domain, ".")
+		if !strings.EqualFold(got, exp) {
+			return HTTPCookieNotMatched(
+				e.Name, fmt.Sprintf("Domain %q", exp), got,
+			)
+		}
+	}
+	lifetime, persistent := cookieLifetime(cookie, time.Now())
+	if e.Session != nil && persistent == *e.Session {
+		if *e.Session {
+			return HTTPCookieNotMatched(e.Name, "no expiry", lifetime.String())
+		}
+		return HTTPCookieNotMatched(e.Name, "an expiry", "no expiry")
+	}
+	if e.Expires != nil {
+		if !persistent {
+			return HTTPCookieNotMatched(
+				e.Name, fmt.Sprintf("expiry %s", e.Expires), "no expiry",
+			)
+		}
+		if !e.Expires.Matches(lifetime) {
+			return HTTPCookieNotMatched(
+				e.Name, fmt.Sprintf("expiry %s", e.Expires), lifetime.String(),
+			)
+		}
+	}
+	return nil
+}
+
+// cookieLifetime returns the time from now until the supplied cookie expires
+// and whether the cookie has an expiry at all. Max-Age takes precedence over
+// Expires.
+func cookieLifetime(c *nethttp.Cookie, now time.Time) (time.Duration, bool) {
+	switch {
+	case c.MaxAge > 0:
+		return time.Duration(c.MaxAge) * time.Second, true
+	case c.MaxAge < 0:
+		return 0, true
+	case !c.Expires.IsZero():
+		return max(c.Expires.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sameSiteString returns the lowercase name of the supplied SameSite mode, or
+// the empty string if the cookie has no SameSite attribute.
+func sameSiteString(mode nethttp.SameSite) string {
+	switch mode {
+	case nethttp.SameSiteStrictMode:
+		return SameSiteStrict
+	case nethttp.SameSiteLaxMode:
+		return SameSiteLax
+	case nethttp.SameSiteNoneMode:
+		return SameSiteNone
+	}
+	return ""
+}
@@ -0,0 +1,135 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	nethttp "net/http"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// BodyFormatJSON indicates the response body contains a JSON document.
+	BodyFormatJSON = "json"
+	// BodyFormatYAML indicates the response body contains a YAML document.
+	BodyFormatYAML = "yaml"
+	// BodyFormatNDJSON indicates the response body contains newline-delimited
+	// JSON, with one JSON document per line.
+	BodyFormatNDJSON = "ndjson"
+)
+
+var validBodyFormats = []string{
+	BodyFormatJSON,
+	BodyFormatYAML,
+	BodyFormatNDJSON,
+}
+
+// responseFormat returns the format of the supplied response's body. The
+// format in the supplied Expect takes precedence over the format indicated by
+// the response's Content-Type HTTP header. Bodies of any other Content-Type
+// are assumed to be JSON.
+func responseFormat(exp *Expect, resp *nethttp.Response) string {
+	if exp != nil && exp.Format != "" {
+		return exp.Format
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "application/yaml",
+		mediaType == "application/x-yaml",
+		mediaType == "text/yaml",
+		mediaType == "text/x-yaml",
+		strings.HasSuffix(mediaType, "+yaml"):
+		return BodyFormatYAML
+	case mediaType == "application/x-ndjson",
+		mediaType == "application/ndjson",
+		mediaType == "application/jsonl",
+		mediaType == "application/x-jsonlines",
+		mediaType == "application/jsonlines":
+		return BodyFormatNDJSON
+	}
+	return BodyFormatJSON
+}
+
+// jsonBody returns the supplied response body, which is in the supplied
+// format, as a JSON document. A YAML body is converted to the equivalent JSON
+// document and an NDJSON body is converted to a JSON array containing the
+// document on each line.
+func jsonBody(format string, b []byte) ([]byte, error) {
+	switch format {
+	case BodyFormatYAML:
+		var doc any
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return nil, err
+		}
+		return json.Marshal(jsonCompatible(doc))
+	case BodyFormatNDJSON:
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for x, line := range bytes.Split(b, []byte("\n")) {
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+			if !json.Valid(line) {
+				return nil, fmt.Errorf("line %d is not valid JSON", x+1)
+			}
+			if buf.Len() > 1 {
+				buf.WriteByte(',')
+			}
+			buf.Write(line)
+		}
+		buf.WriteByte(']')
+		return buf.Bytes(), nil
+	}
+	return b, nil
+}
+
+// decodeBody decodes the supplied response body, which is in the supplied
+// format, into the same types that encoding/json decodes a JSON document
+// into.
+func decodeBody(format string, b []byte) (any, error) {
+	if len(b) == 0 {
+		return nil, ErrEmptyBody
+	}
+	js, err := jsonBody(format, b)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := json.Unmarshal(js, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// jsonCompatible returns the supplied decoded YAML value with any maps having
+// non-string keys converted to maps with string keys, so that the value can
+// be encoded as JSON.
+func jsonCompatible(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, val := range v {
+			v[key] = jsonCompatible(val)
+		}
+		return v
+	case map[any]any:
+		res := make(map[string]any, len(v))
+		for key, val := range v {
+			res[fmt.Sprint(key)] = jsonCompatible(val)
+		}
+		return res
+	case []any:
+		for x, val := range v {
+			v[x] = jsonCompatible(val)
+		}
+		return v
+	}
+	return v
+}
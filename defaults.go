@@ -6,6 +6,9 @@ package http
 
 import (
 	"context"
+	nethttp "net/http"
+	"net/http/cookiejar"
+	"sync"
 
 	"github.com/gdt-dev/core/api"
 	gdtcontext "github.com/gdt-dev/core/context"
@@ -20,11 +23,36 @@ type httpDefaults struct {
 	//
 	// See the `httpServerFixture` for an example of how this works.
 	BaseURL string `yaml:"base_url,omitempty"`
+	// Auth describes the credentials sent along in the HTTP requests made by
+	// the gdt-http plugin's Specs. A Spec may override these credentials by
+	// specifying its own `auth` field.
+	Auth *Auth `yaml:"auth,omitempty"`
+	// CookieJar, if true, stores the cookies set by HTTP responses and sends
+	// them along in subsequent HTTP requests made by the test specs in the
+	// same scenario.
+	CookieJar bool `yaml:"cookie_jar,omitempty"`
+	// FollowRedirects is whether, or how many, HTTP redirects are followed
+	// by the HTTP requests made by the gdt-http plugin's Specs. It may be a
+	// bool or the maximum number of redirects to follow. A Spec may override
+	// this by specifying its own `follow_redirects` field.
+	FollowRedirects *RedirectPolicy `yaml:"follow_redirects,omitempty"`
+	// TLS describes the TLS configuration of the HTTP client used by the
+	// gdt-http plugin's Specs, such as additional trusted CA certificates
+	// and a client certificate.
+	TLS *TLSConfig `yaml:"tls,omitempty"`
 }
 
 // Defaults is the known HTTP plugin defaults collection
 type Defaults struct {
 	httpDefaults
+	// jar is the scenario's cookie jar, if the CookieJar default is true.
+	jar nethttp.CookieJar
+	// transport is the HTTP transport with the TLS configuration applied,
+	// created the first time it is needed.
+	transport *nethttp.Transport
+	// transportOnce ensures transport is only created once so that
+	// connections are reused by the scenario's test specs.
+	transportOnce sync.Once
 }
 
 // Merge merges the supplies map of key/value combinations with the set of
@@ -70,6 +98,15 @@ func (d *Defaults) UnmarshalYAML(node *yaml.Node) error {
 				return err
 			}
 			d.httpDefaults = hd
+			if hd.CookieJar {
+				// Defaults are parsed once per scenario, so the cookie jar
+				// is shared by all of the scenario's test specs.
+				jar, err := cookiejar.New(nil)
+				if err != nil {
+					return err
+				}
+				d.jar = jar
+			}
 		default:
 			continue
 		}
@@ -77,6 +114,16 @@ func (d *Defaults) UnmarshalYAML(node *yaml.Node) error {
 	return nil
 }
 
+// tlsTransport returns the HTTP transport with the TLS configuration applied
+// to a copy of the supplied HTTP transport. The HTTP transport is created
+// once per scenario.
+func (d *Defaults) tlsTransport(base nethttp.RoundTripper) *nethttp.Transport {
+	d.transportOnce.Do(func() {
+		d.transport = d.TLS.transport(base)
+	})
+	return d.transport
+}
+
 // BaseURLFromContext returns the base URL to use when constructing HTTP
 // requests. If the Defaults is non-nil and has a BaseURL value, use that.
 // Otherwise we look up a base URL from the context's fixtures.
@@ -5,9 +5,13 @@
 package http
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gdt-dev/core/api"
+	"github.com/pmezard/go-difflib/difflib"
 )
 
 var (
@@ -19,8 +23,86 @@ var (
 		"%w: expected Location HTTP Header in previous response",
 		api.RuntimeError,
 	)
+	// ErrVariableNotFound indicates that the user referenced a variable with
+	// the `$(name)` syntax but no prior test spec saved a variable with that
+	// name and no fixture has a state key with that name.
+	ErrVariableNotFound = fmt.Errorf(
+		"%w: variable not found",
+		api.RuntimeError,
+	)
+	// ErrVarFromBodyNotJSON indicates that the `var.$VAR.from` JSONPath
+	// expression could not be evaluated because the response body was not
+	// valid JSON, YAML or NDJSON.
+	ErrVarFromBodyNotJSON = fmt.Errorf(
+		"%w: var.from requires a JSON, YAML or NDJSON response body",
+		api.RuntimeError,
+	)
+	// ErrVarXPathNotFound indicates that the `var.$VAR.xpath` XPath
+	// expression could not be evaluated because the response body was not
+	// valid XML or did not select anything in the response body.
+	ErrVarXPathNotFound = fmt.Errorf(
+		"%w: var.xpath not found",
+		api.RuntimeError,
+	)
+	// ErrVarSelectorNotFound indicates that the `var.$VAR.selector` CSS
+	// selector did not select any elements in the response body, or that
+	// the selected element does not have the `var.$VAR.attr` attribute.
+	ErrVarSelectorNotFound = fmt.Errorf(
+		"%w: var.selector not found",
+		api.RuntimeError,
+	)
+	// ErrOAuth2TokenRequest indicates that an OAuth2 access token could not
+	// be obtained from the token endpoint.
+	ErrOAuth2TokenRequest = fmt.Errorf(
+		"%w: failed to obtain OAuth2 access token",
+		api.RuntimeError,
+	)
+	// ErrJWKSRequest indicates that the JSON Web Key Set used to verify a
+	// JWT's signature could not be obtained from the `jwks_url`.
+	ErrJWKSRequest = fmt.Errorf(
+		"%w: failed to obtain JSON Web Key Set",
+		api.RuntimeError,
+	)
+	// ErrEmptyBody indicates that the response body was empty.
+	ErrEmptyBody = errors.New("response body is empty")
+	// ErrVarHeaderNotFound indicates that the `var.$VAR.header` HTTP header
+	// was not found in the response.
+	ErrVarHeaderNotFound = fmt.Errorf(
+		"%w: var.header not found",
+		api.RuntimeError,
+	)
+	// ErrVarCookieNotFound indicates that the `var.$VAR.cookie` cookie was
+	// not set by the response.
+	ErrVarCookieNotFound = fmt.Errorf(
+		"%w: var.cookie not found",
+		api.RuntimeError,
+	)
+	// ErrVarRegexNotMatched indicates that the `var.$VAR.regex` regular
+	// expression did not match the response body.
+	ErrVarRegexNotMatched = fmt.Errorf(
+		"%w: var.regex not matched",
+		api.RuntimeError,
+	)
 )
 
+// VariableNotFound returns an ErrVariableNotFound for the supplied variable
+// name.
+func VariableNotFound(name string) error {
+	return fmt.Errorf("%w: %s", ErrVariableNotFound, name)
+}
+
+// OAuth2TokenRequestFailed returns an ErrOAuth2TokenRequest with the supplied
+// reason the access token could not be obtained.
+func OAuth2TokenRequestFailed(reason string) error {
+	return fmt.Errorf("%w: %s", ErrOAuth2TokenRequest, reason)
+}
+
+// JWKSRequestFailed returns an ErrJWKSRequest with the supplied reason the
+// JSON Web Key Set could not be obtained.
+func JWKSRequestFailed(reason string) error {
+	return fmt.Errorf("%w: %s", ErrJWKSRequest, reason)
+}
+
 // HTTPStatusNotEqual returns an ErrNotEqual when an expected thing doesn't equal an
 // observed thing.
 func HTTPStatusNotEqual(exp, got interface{}) error {
@@ -39,6 +121,73 @@ func HTTPHeaderNotIn(element, container interface{}) error {
 	)
 }
 
+// HTTPHeaderPresent returns an ErrIn when a header that is expected to be
+// absent appears in a response's headers.
+func HTTPHeaderPresent(header string, vals []string) error {
+	return fmt.Errorf(
+		"%w: expected HTTP header %s to be absent but got %v",
+		api.ErrIn, header, vals,
+	)
+}
+
+// HTTPHeaderNotMatched returns an ErrNotEqual when the value of a response's
+// header does not satisfy an expected condition.
+func HTTPHeaderNotMatched(header string, cond string, got string) error {
+	return fmt.Errorf(
+		"%w: expected HTTP header %s %s but got %q",
+		api.ErrNotEqual, header, cond, got,
+	)
+}
+
+// HTTPCookieNotIn returns an ErrNotIn when a response does not set an
+// expected cookie.
+func HTTPCookieNotIn(name string) error {
+	return fmt.Errorf(
+		"%w: expected HTTP response to set cookie %s",
+		api.ErrNotIn, name,
+	)
+}
+
+// HTTPCookiePresent returns an ErrIn when a response sets a cookie that is
+// expected to be absent.
+func HTTPCookiePresent(name string) error {
+	return fmt.Errorf(
+		"%w: expected cookie %s to be absent",
+		api.ErrIn, name,
+	)
+}
+
+// HTTPCookieNotMatched returns an ErrNotEqual when a cookie set by a response
+// does not satisfy an expected condition.
+func HTTPCookieNotMatched(name string, cond string, got string) error {
+	return fmt.Errorf(
+		"%w: expected cookie %s to have %s but got %q",
+		api.ErrNotEqual, name, cond, got,
+	)
+}
+
+// HTTPRedirectCountNotEqual returns an ErrNotEqual when the number of
+// redirects followed to arrive at a response is not the expected number.
+func HTTPRedirectCountNotEqual(exp int, hops []*RedirectHop) error {
+	got := make([]string, len(hops))
+	for x, hop := range hops {
+		got[x] = hop.String()
+	}
+	return fmt.Errorf(
+		"%w: expected %d redirects but got %d [%s]",
+		api.ErrNotEqual, exp, len(hops), strings.Join(got, ", "),
+	)
+}
+
+// HTTPRedirectNotMatched returns an ErrNotEqual when a redirect followed to
+// arrive at a response does not satisfy an expected condition.
+func HTTPRedirectNotMatched(num int, cond string, got string) error {
+	return fmt.Errorf(
+		"%w: expected redirect %d to have %s but got %q",
+		api.ErrNotEqual, num, cond, got,
+	)
+}
+
 // HTTPNotInBody returns an ErrNotIn when an expected thing doesn't appear in a
 // a response's Body.
 func HTTPNotInBody(element string) error {
@@ -47,3 +196,330 @@ func HTTPNotInBody(element string) error {
 		api.ErrNotIn, element,
 	)
 }
+
+// HTTPInBody returns an ErrIn when an unexpected thing appears in a
+// response's Body.
+func HTTPInBody(element string) error {
+	return fmt.Errorf(
+		"%w: expected HTTP body not to contain %v",
+		api.ErrIn, element,
+	)
+}
+
+// HTTPBodyNotMatched returns an ErrNotEqual when a response's Body does not
+// match an expected regular expression.
+func HTTPBodyNotMatched(expr string) error {
+	return fmt.Errorf(
+		"%w: expected HTTP body to match %s",
+		api.ErrNotEqual, expr,
+	)
+}
+
+// HTTPBodyNotEqual returns an ErrNotEqual when a response's Body does not
+// equal the expected content. The returned error contains a unified diff of
+// the expected content, labelled with the supplied name, and the response's
+// Body.
+func HTTPBodyNotEqual(name string, exp string, got string) error {
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(exp),
+		B:        difflib.SplitLines(got),
+		FromFile: name,
+		ToFile:   "response",
+		Context:  3,
+	})
+	return fmt.Errorf(
+		"%w: expected HTTP body to equal %s:\n%s",
+		api.ErrNotEqual, name, diff,
+	)
+}
+
+// HTTPBodyNotDecoded returns an ErrFailure when a response's Body is expected
+// to contain content in the supplied format but could not be decoded.
+func HTTPBodyNotDecoded(format string, err error) error {
+	return fmt.Errorf(
+		"%w: expected HTTP body to contain %s: %s",
+		api.ErrFailure, strings.ToUpper(format), err,
+	)
+}
+
+// HTTPBodyNotXML returns an ErrFailure when a response's Body is expected to
+// contain XML but could not be parsed as XML.
+func HTTPBodyNotXML(err error) error {
+	return fmt.Errorf(
+		"%w: expected HTTP body to contain XML: %s",
+		api.ErrFailure, err,
+	)
+}
+
+// HTTPXMLSchemaInvalid returns an ErrFailure when XML content in a response's
+// Body does not validate against an XSD document.
+func HTTPXMLSchemaInvalid(path string, err error) error {
+	return fmt.Errorf(
+		"%w: XML content did not adhere to XSD %s: %s",
+		api.ErrFailure, path, err,
+	)
+}
+
+// HTTPXPathNotFound returns an ErrNotIn when an XPath expression does not
+// select anything in the XML content of a response's Body.
+func HTTPXPathNotFound(expr string) error {
+	return fmt.Errorf(
+		"%w: expected XML content to contain XPath %s",
+		api.ErrNotIn, expr,
+	)
+}
+
+// HTTPXPathNotEqual returns an ErrNotEqual when the value selected by an
+// XPath expression does not equal the expected value.
+func HTTPXPathNotEqual(expr string, exp string, got string) error {
+	return fmt.Errorf(
+		"%w: expected %q but got %q at XPath %s",
+		api.ErrNotEqual, exp, got, expr,
+	)
+}
+
+// HTTPXPathCountNotEqual returns an ErrNotEqual when an XPath expression
+// does not select the expected number of nodes.
+func HTTPXPathCountNotEqual(expr string, exp int, got int) error {
+	return fmt.Errorf(
+		"%w: expected %d nodes but got %d at XPath %s",
+		api.ErrNotEqual, exp, got, expr,
+	)
+}
+
+// HTTPSelectorNotFound returns an ErrNotIn when a CSS selector does not
+// select any elements in the HTML content of a response's Body.
+func HTTPSelectorNotFound(selector string) error {
+	return fmt.Errorf(
+		"%w: expected HTML content to contain elements matching %s",
+		api.ErrNotIn, selector,
+	)
+}
+
+// HTTPSelectorNotMatched returns an ErrNotEqual when the elements selected by
+// a CSS selector do not satisfy an expected condition.
+func HTTPSelectorNotMatched(selector string, cond string, got string) error {
+	return fmt.Errorf(
+		"%w: expected elements matching %s to have %s but got %q",
+		api.ErrNotEqual, selector, cond, got,
+	)
+}
+
+// HTTPLatencyNotMatched returns an ErrFailure when the time spent in a phase
+// of the HTTP request does not satisfy the expected condition.
+func HTTPLatencyNotMatched(
+	phase string,
+	cond *DurationCondition,
+	got time.Duration,
+) error {
+	return fmt.Errorf(
+		"%w: expected %s latency %s but got %s",
+		api.ErrFailure, phase, cond, got,
+	)
+}
+
+// HTTPJWTNotFound returns an ErrNotIn when a response does not contain a JWT
+// at the expected location.
+func HTTPJWTNotFound(source string) error {
+	return fmt.Errorf(
+		"%w: expected JWT in %s",
+		api.ErrNotIn, source,
+	)
+}
+
+// HTTPJWTInvalid returns an ErrFailure when a JWT in a response could not be
+// decoded.
+func HTTPJWTInvalid(err error) error {
+	return fmt.Errorf(
+		"%w: expected valid JWT: %s",
+		api.ErrFailure, err,
+	)
+}
+
+// HTTPJWTSignatureInvalid returns an ErrFailure when the signature of a JWT
+// in a response could not be verified.
+func HTTPJWTSignatureInvalid(err error) error {
+	return fmt.Errorf(
+		"%w: JWT signature could not be verified: %s",
+		api.ErrFailure, err,
+	)
+}
+
+// HTTPJWTHeaderNotEqual returns an ErrNotEqual when a JWT's header does not
+// have the expected value.
+func HTTPJWTHeaderNotEqual(name string, exp string, got string) error {
+	return fmt.Errorf(
+		"%w: expected JWT header %s to be %q but got %q",
+		api.ErrNotEqual, name, exp, got,
+	)
+}
+
+// HTTPJWTClaimNotFound returns an ErrNotIn when a JWT does not contain an
+// expected claim.
+func HTTPJWTClaimNotFound(name string) error {
+	return fmt.Errorf(
+		"%w: expected JWT to contain claim %s",
+		api.ErrNotIn, name,
+	)
+}
+
+// HTTPJWTClaimNotEqual returns an ErrNotEqual when a JWT's claim does not
+// have the expected value.
+func HTTPJWTClaimNotEqual(name string, exp string, got string) error {
+	return fmt.Errorf(
+		"%w: expected JWT claim %s to be %q but got %q",
+		api.ErrNotEqual, name, exp, got,
+	)
+}
+
+// HTTPJWTExpired returns an ErrFailure when a JWT is expected to be valid
+// now but is expired, not yet valid or has no expiry.
+func HTTPJWTExpired(reason string) error {
+	return fmt.Errorf(
+		"%w: expected JWT not to be expired: %s",
+		api.ErrFailure, reason,
+	)
+}
+
+// HTTPJWTScopeNotGranted returns an ErrNotIn when a JWT does not grant an
+// expected scope.
+func HTTPJWTScopeNotGranted(scope string, granted []string) error {
+	return fmt.Errorf(
+		"%w: expected JWT to grant scope %s but got %q",
+		api.ErrNotIn, scope, strings.Join(granted, " "),
+	)
+}
+
+// HTTPTLSNotUsed returns an ErrFailure when a response that is expected to be
+// received over TLS was not.
+func HTTPTLSNotUsed() error {
+	return fmt.Errorf(
+		"%w: expected HTTP response to be received over TLS",
+		api.ErrFailure,
+	)
+}
+
+// HTTPTLSNotMatched returns an ErrNotEqual when a property of the TLS
+// connection or of the server's certificate does not have the expected value.
+func HTTPTLSNotMatched(property string, exp string, got string) error {
+	return fmt.Errorf(
+		"%w: expected TLS %s %s but got %q",
+		api.ErrNotEqual, property, exp, got,
+	)
+}
+
+// HTTPCertificateSANNotIn returns an ErrNotIn when the server's certificate
+// does not have an expected subject alternative name.
+func HTTPCertificateSANNotIn(san string, sans []string) error {
+	return fmt.Errorf(
+		"%w: expected TLS certificate subject alternative names %v to "+
+			"contain %s",
+		api.ErrNotIn, sans, san,
+	)
+}
+
+// HTTPSnapshotMissing returns an ErrFailure when a snapshot file does not
+// exist and snapshots are not being updated.
+func HTTPSnapshotMissing(path string) error {
+	return fmt.Errorf(
+		"%w: snapshot %s does not exist. set %s=1 to record it",
+		api.ErrFailure, path, EnvUpdateSnapshots,
+	)
+}
+
+// VarHeaderNotFound returns an ErrVarHeaderNotFound indicating that a variable
+// could not be populated because the HTTP response did not contain the
+// variable's header.
+func VarHeaderNotFound(varName string, header string) error {
+	return fmt.Errorf(
+		"%w: variable %s could not be filled because "+
+			"HTTP header %s was not in response",
+		ErrVarHeaderNotFound, varName, header,
+	)
+}
+
+// VarCookieNotFound returns an ErrVarCookieNotFound indicating that a
+// variable could not be populated because the HTTP response did not set the
+// variable's cookie.
+func VarCookieNotFound(varName string, cookie string) error {
+	return fmt.Errorf(
+		"%w: variable %s could not be filled because "+
+			"cookie %s was not set in response",
+		ErrVarCookieNotFound, varName, cookie,
+	)
+}
+
+// VarRegexNotMatched returns an ErrVarRegexNotMatched indicating that a
+// variable could not be populated because the variable's regular expression
+// did not match the HTTP response body.
+func VarRegexNotMatched(varName string, expr string) error {
+	return fmt.Errorf(
+		"%w: variable %s could not be filled because "+
+			"regular expression %s did not match response body",
+		ErrVarRegexNotMatched, varName, expr,
+	)
+}
+
+// VarFromBodyNotJSON returns an ErrVarFromBodyNotJSON indicating that a
+// variable could not be populated because the HTTP response body could not be
+// decoded as JSON.
+func VarFromBodyNotJSON(varName string, err error) error {
+	return fmt.Errorf(
+		"%w: variable %s could not be filled: %s. "+
+			"use var.regex to extract values from non-JSON bodies",
+		ErrVarFromBodyNotJSON, varName, err,
+	)
+}
+
+// VarFromBodyNotDecoded returns an ErrVarFromBodyNotJSON indicating that a
+// variable could not be populated because the HTTP response body could not be
+// decoded from the supplied format.
+func VarFromBodyNotDecoded(varName string, format string, err error) error {
+	if format == BodyFormatJSON {
+		return VarFromBodyNotJSON(varName, err)
+	}
+	return fmt.Errorf(
+		"%w: variable %s could not be filled: invalid %s: %s",
+		ErrVarFromBodyNotJSON, varName, strings.ToUpper(format), err,
+	)
+}
+
+// VarXPathNotFound returns an ErrVarXPathNotFound indicating that a variable
+// could not be populated because the variable's XPath expression did not
+// select anything in the HTTP response body.
+func VarXPathNotFound(varName string, expr string) error {
+	return fmt.Errorf(
+		"%w: variable %s could not be filled because "+
+			"XPath %s did not select anything in response body",
+		ErrVarXPathNotFound, varName, expr,
+	)
+}
+
+// VarBodyNotXML returns an ErrVarXPathNotFound indicating that a variable
+// could not be populated because the HTTP response body could not be parsed
+// as XML.
+func VarBodyNotXML(varName string, err error) error {
+	return fmt.Errorf(
+		"%w: variable %s could not be filled: %s",
+		ErrVarXPathNotFound, varName, err,
+	)
+}
+
+// VarSelectorNotFound returns an ErrVarSelectorNotFound indicating that a
+// variable could not be populated because the variable's CSS selector did not
+// select any elements in the HTTP response body or the selected element does
+// not have the variable's attribute.
+func VarSelectorNotFound(varName string, selector string, attr string) error {
+	if attr != "" {
+		return fmt.Errorf(
+			"%w: variable %s could not be filled because "+
+				"no element matching %s with attribute %s was in response body",
+			ErrVarSelectorNotFound, varName, selector, attr,
+		)
+	}
+	return fmt.Errorf(
+		"%w: variable %s could not be filled because "+
+			"no element matching %s was in response body",
+		ErrVarSelectorNotFound, varName, selector,
+	)
+}
@@ -18,11 +18,12 @@ import (
 // Run executes the test described by the HTTP test. A new HTTP request and
 // response pair is created during this call.
 func (s *Spec) Eval(ctx context.Context) (*api.Result, error) {
-	c := client(ctx)
 	defaults := fromBaseDefaults(s.Defaults)
+	c := client(ctx, defaults)
 	runData := &RunData{}
+	timings := &Timings{}
 
-	resp, err := s.HTTP.Do(ctx, c, defaults)
+	resp, err := s.HTTP.do(ctx, c, defaults, timings)
 	if err != nil {
 		if err == api.ErrTimeoutExceeded {
 			return api.NewResult(api.WithFailures(api.ErrTimeoutExceeded)), nil
@@ -42,15 +43,30 @@ func (s *Spec) Eval(ctx context.Context) (*api.Result, error) {
 	if err != nil {
 		return nil, err
 	}
+	timings.done()
+	debug.Printf(
+		ctx, "http: < timings: dns=%s connect=%s tls=%s ttfb=%s total=%s",
+		timings.DNS, timings.Connect, timings.TLS, timings.TTFB, timings.Total,
+	)
 	if len(body) > 0 {
 		debug.Printf(ctx, "http: < %s", string(body))
 	}
-	a := newAssertions(s.Assert, resp, body)
+	var jwtKey any
+	if s.Assert != nil {
+		jwtKey, err = s.Assert.JWT.verificationKey(ctx, c, defaults)
+		if err != nil {
+			return nil, err
+		}
+	}
+	a := newAssertions(s.Assert, resp, body, timings, jwtKey)
 	if a.OK(ctx) {
 		runData.Response = resp
+		runData.Timings = timings
 		res := api.NewResult()
 		res.SetData(pluginName, runData)
-		if err := saveVars(ctx, s.Var, body, res); err != nil {
+		if err := saveVars(
+			ctx, s.Var, resp, body, responseFormat(s.Assert, resp), timings, res,
+		); err != nil {
 			return nil, err
 		}
 		return res, nil
@@ -60,8 +76,43 @@ func (s *Spec) Eval(ctx context.Context) (*api.Result, error) {
 
 // client returns the HTTP client to use when executing HTTP requests. If any
 // fixture provides a state with key "http.client", the fixture is asked for
-// the HTTP client. Otherwise, we use the net/http.DefaultClient
-func client(ctx context.Context) *nethttp.Client {
+// the HTTP client. Otherwise, we use the net/http.DefaultClient. If the
+// scenario's defaults have a cookie jar or a TLS configuration, a copy of the
+// HTTP client that uses the cookie jar and the TLS configuration is returned.
+func client(ctx context.Context, defaults *Defaults) *nethttp.Client {
+	c := baseClient(ctx)
+	if defaults == nil || (defaults.jar == nil && defaults.TLS == nil) {
+		return c
+	}
+	dc := *c
+	if defaults.jar != nil {
+		dc.Jar = defaults.jar
+	}
+	if defaults.TLS != nil {
+		dc.Transport = defaults.tlsTransport(c.Transport)
+	}
+	return &dc
+}
+
+// tokenClient returns the HTTP client used to request OAuth2 access tokens.
+// It is the base HTTP client with the TLS configuration in the supplied
+// defaults applied but, unlike the client used for the test spec's HTTP
+// request, without the scenario's cookie jar or the test spec's redirect
+// policy, so that how access tokens are obtained does not depend on the test
+// spec that first needed one.
+func tokenClient(ctx context.Context, defaults *Defaults) *nethttp.Client {
+	c := baseClient(ctx)
+	if defaults == nil || defaults.TLS == nil {
+		return c
+	}
+	tc := *c
+	tc.Transport = defaults.tlsTransport(c.Transport)
+	return &tc
+}
+
+// baseClient returns the HTTP client provided by a fixture or the
+// net/http.DefaultClient.
+func baseClient(ctx context.Context) *nethttp.Client {
 	// query the fixture registry to determine if any of them contain an
 	// http.client state attribute.
 	fixtures := gdtcontext.Fixtures(ctx)
@@ -82,6 +133,8 @@ func client(ctx context.Context) *nethttp.Client {
 // `$LOCATION` URL value.
 type RunData struct {
 	Response *nethttp.Response
+	// Timings contains the time spent in each phase of the HTTP request.
+	Timings *Timings
 }
 
 // priorRunData returns any prior run cached data in the context.
@@ -5,14 +5,21 @@
 package http_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"log"
+	nethttp "net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/gdt-dev/core/api"
+	gdtjson "github.com/gdt-dev/core/assertion/json"
 	gdtcontext "github.com/gdt-dev/core/context"
 	gdtjsonfix "github.com/gdt-dev/core/fixture/json"
 	"github.com/gdt-dev/core/scenario"
@@ -56,6 +63,68 @@ func dataFixture() api.Fixture {
 	return fix
 }
 
+// mtlsFixture serves the books API over TLS using the server certificate in
+// testdata/tls and requires clients to present a certificate signed by the CA
+// in testdata/tls. Unlike the fixture returned by gdthttp.NewServerFixture, it
+// only provides a base URL and not an HTTP client, so test scenarios must
+// configure TLS in their `http` defaults.
+type mtlsFixture struct {
+	handler   nethttp.Handler
+	tlsConfig *tls.Config
+	server    *httptest.Server
+}
+
+// newMTLSFixture returns an mtlsFixture. The certificates and books are
+// loaded up front because scenarios are run from the scenario file's
+// directory.
+func newMTLSFixture() *mtlsFixture {
+	cert, err := tls.LoadX509KeyPair(
+		filepath.Join("testdata", "tls", "server.pem"),
+		filepath.Join("testdata", "tls", "server-key.pem"),
+	)
+	if err != nil {
+		panic(err)
+	}
+	ca, err := os.ReadFile(filepath.Join("testdata", "tls", "ca.pem"))
+	if err != nil {
+		panic(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(ca)
+	logger := log.New(os.Stdout, "books_api_mtls: ", log.LstdFlags)
+	srv := server.NewControllerWithBooks(logger, data().Books)
+	return &mtlsFixture{
+		handler: srv.Router(),
+		tlsConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+		},
+	}
+}
+
+func (f *mtlsFixture) Start(ctx context.Context) error {
+	f.server = httptest.NewUnstartedServer(f.handler)
+	f.server.TLS = f.tlsConfig
+	f.server.StartTLS()
+	return nil
+}
+
+func (f *mtlsFixture) Stop(ctx context.Context) {
+	f.server.Close()
+}
+
+func (f *mtlsFixture) HasState(key string) bool {
+	return key == gdthttp.StateKeyBaseURL
+}
+
+func (f *mtlsFixture) State(key string) interface{} {
+	if key == gdthttp.StateKeyBaseURL {
+		return f.server.URL
+	}
+	return nil
+}
+
 func TestFixturesNotSetup(t *testing.T) {
 	require := require.New(t)
 	assert := assert.New(t)
@@ -85,6 +154,33 @@ func setup(ctx context.Context) context.Context {
 	return ctx
 }
 
+// evalFailures starts the scenario's fixtures and evaluates each of the
+// scenario's test specs once, returning the first failure of each test spec,
+// or nil if the test spec passed. Unlike Scenario.Run, failing test specs are
+// not retried and their failures are not reported to t.
+func evalFailures(
+	ctx context.Context,
+	t *testing.T,
+	s *scenario.Scenario,
+) []error {
+	fixtures := gdtcontext.Fixtures(ctx)
+	for _, name := range s.Fixtures {
+		fix := fixtures[name]
+		require.NotNil(t, fix)
+		require.Nil(t, fix.Start(ctx))
+		defer fix.Stop(ctx)
+	}
+	failures := make([]error, len(s.Tests))
+	for x, st := range s.Tests {
+		res, err := st.Eval(ctx)
+		require.Nil(t, err)
+		if res.Failed() {
+			failures[x] = res.Failures()[0]
+		}
+	}
+	return failures
+}
+
 func TestCreateThenGet(t *testing.T) {
 	require := require.New(t)
 
@@ -156,3 +252,1152 @@ func TestPutMultipleBooks(t *testing.T) {
 
 	s.Run(ctx, t)
 }
+
+func TestHeaders(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "headers.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestQuery(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "query.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestBody(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "body.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestPayloadFiles(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "payload-files.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestInterpolation(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "interpolation.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestUnknownVariable(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	fp := filepath.Join("testdata", "unknown-variable.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.NotNil(err)
+	assert.ErrorIs(err, gdthttp.ErrVariableNotFound)
+	assert.ErrorContains(err, "nosuchvar")
+}
+
+func TestVarSources(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "var-sources.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestVarJSONRoots(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "var-json-roots.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestVarBodyNotJSON(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	fp := filepath.Join("testdata", "var-body-not-json.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.NotNil(err)
+	assert.ErrorIs(err, gdthttp.ErrVarFromBodyNotJSON)
+	assert.ErrorIs(err, api.RuntimeError)
+}
+
+func TestAuth(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	apiKey := "s3cr3t-api-key"
+	t.Setenv("GDT_HTTP_TEST_API_KEY", apiKey)
+
+	fp := filepath.Join("testdata", "auth.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	var debugOut bytes.Buffer
+	ctx := gdtcontext.New(gdtcontext.WithDebug(&debugOut))
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+
+	out := debugOut.String()
+	assert.Contains(out, "Authorization: Basic REDACTED")
+	assert.Contains(out, "Authorization: Bearer REDACTED")
+	assert.Contains(out, "X-API-Key: REDACTED")
+	assert.Contains(out, "api_key=REDACTED")
+	assert.NotContains(out, apiKey)
+}
+
+func TestHeaderCredentialsMasked(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	apiKey := "s3cr3t-api-key"
+	t.Setenv("GDT_HTTP_TEST_API_KEY", apiKey)
+
+	fp := filepath.Join("testdata", "header-credentials.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	var debugOut bytes.Buffer
+	ctx := gdtcontext.New(gdtcontext.WithDebug(&debugOut))
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+
+	out := debugOut.String()
+	assert.Contains(out, "Authorization: Bearer REDACTED")
+	assert.Contains(out, "X-Request-Token: REDACTED")
+	assert.Contains(out, "X-API-Key: REDACTED")
+	assert.Contains(out, "Cookie: session=REDACTED")
+	assert.NotContains(out, apiKey)
+	assert.NotRegexp(`Bearer [^R]`, out)
+	assert.NotRegexp(`session=[^R]`, out)
+}
+
+func TestOAuth2(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	// The secret contains characters that must be form-urlencoded before
+	// being sent using HTTP Basic authentication.
+	clientSecret := "s3:cr3t%+"
+	t.Setenv("GDT_HTTP_TEST_CLIENT_SECRET", clientSecret)
+
+	fp := filepath.Join("testdata", "oauth2.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	var debugOut bytes.Buffer
+	ctx := gdtcontext.New(gdtcontext.WithDebug(&debugOut))
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+
+	out := debugOut.String()
+	assert.Contains(out, "Authorization: Bearer REDACTED")
+	assert.NotContains(out, clientSecret)
+}
+
+func TestOAuth2TokenRedirect(t *testing.T) {
+	require := require.New(t)
+
+	t.Setenv("GDT_HTTP_TEST_CLIENT_SECRET", "s3:cr3t%+")
+
+	fp := filepath.Join("testdata", "oauth2-token-redirect.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestOAuth2BadClient(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	fp := filepath.Join("testdata", "oauth2-bad-client.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.NotNil(err)
+	assert.ErrorIs(err, gdthttp.ErrOAuth2TokenRequest)
+	assert.ErrorContains(err, "invalid_client")
+}
+
+func TestCookieJar(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "cookie-jar.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestCookies(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "cookies.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestMethods(t *testing.T) {
+	require := require.New(t)
+
+	require.Nil(gdthttp.RegisterMethod("PROPFIND"))
+
+	fp := filepath.Join("testdata", "methods.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestStatus(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "status.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestStatusFailures(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "status-failures.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	failures := evalFailures(ctx, t, s)
+	expErrs := []string{
+		"expected HTTP status one of 201, 204 but got 200",
+		"expected HTTP status 4xx but got 200",
+		"expected HTTP status 201-299 but got 200",
+		"expected HTTP status not 2xx but got 200",
+		"expected HTTP status not 404, 5xx but got 404",
+	}
+	require.Len(failures, len(expErrs))
+	for x, expErr := range expErrs {
+		require.ErrorIs(failures[x], api.ErrNotEqual, s.Tests[x].Base().Title())
+		require.ErrorContains(failures[x], expErr)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "latency.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestLatencyFailures(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "latency-failures.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	failures := evalFailures(ctx, t, s)
+	expErrs := []string{
+		"expected total latency <1ns but got ",
+		"expected ttfb latency <1ns but got ",
+		"expected total latency >=1h0m0s but got ",
+	}
+	require.Len(failures, len(expErrs))
+	for x, expErr := range expErrs {
+		require.ErrorIs(failures[x], api.ErrFailure, s.Tests[x].Base().Title())
+		require.ErrorContains(failures[x], expErr)
+	}
+}
+
+func TestLatencyRedirects(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "latency-redirects.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestXML(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "xml.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestXMLFailures(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "xml-failures.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	failures := evalFailures(ctx, t, s)
+	expErrs := []struct {
+		is  error
+		msg string
+	}{
+		{
+			api.ErrNotIn,
+			`expected XML content to contain XPath //book[author="F. Scott Fitzgerald"]`,
+		},
+		{
+			api.ErrNotEqual,
+			`expected "For Whom the Bell Tolls" but got "Old Man and the Sea" ` +
+				"at XPath /catalog/book[1]/title",
+		},
+		{api.ErrNotEqual, "expected 2 nodes but got 1 at XPath //book"},
+	}
+	require.Len(failures, len(expErrs))
+	for x, exp := range expErrs {
+		require.ErrorIs(failures[x], exp.is, s.Tests[x].Base().Title())
+		require.ErrorContains(failures[x], exp.msg)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "html.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestHTMLFailures(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "html-failures.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	failures := evalFailures(ctx, t, s)
+	expErrs := []struct {
+		is  error
+		msg string
+	}{
+		{api.ErrNotIn, "expected HTML content to contain elements matching form#delete-book"},
+		{api.ErrNotEqual, `to have text "For Whom the Bell Tolls" but got "Old Man and the Sea"`},
+		{api.ErrNotEqual, `to have data-id="not-a-book-id" but got "12ac1b94-5667-461e-80cb-ba8619cae61a"`},
+		{api.ErrNotEqual, `expected elements matching #books tbody tr to have count 2 but got "1"`},
+	}
+	require.Len(failures, len(expErrs))
+	for x, exp := range expErrs {
+		require.ErrorIs(failures[x], exp.is, s.Tests[x].Base().Title())
+		require.ErrorContains(failures[x], exp.msg)
+	}
+}
+
+func TestBodyFormats(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "body-formats.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+// isbn13 returns true if the supplied value is an ISBN-13 string, ignoring
+// hyphens, with a valid check digit.
+func isbn13(input any) bool {
+	s, ok := input.(string)
+	if !ok {
+		return false
+	}
+	s = strings.ReplaceAll(s, "-", "")
+	if len(s) != 13 {
+		return false
+	}
+	sum := 0
+	for x, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+		weight := 1
+		if x%2 == 1 {
+			weight = 3
+		}
+		sum += int(r-'0') * weight
+	}
+	return sum%10 == 0
+}
+
+func TestFormats(t *testing.T) {
+	require := require.New(t)
+
+	gdthttp.RegisterFormat("isbn13", gdthttp.FormatCheckerFunc(isbn13))
+
+	fp := filepath.Join("testdata", "formats.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestFormatsMalformed(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	gdthttp.RegisterFormat("isbn13", gdthttp.FormatCheckerFunc(isbn13))
+
+	fp := filepath.Join("testdata", "formats-malformed.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	failures := evalFailures(ctx, t, s)
+	require.Len(failures, 3)
+	assert.ErrorIs(failures[0], gdtjson.ErrJSONFormatNotEqual)
+	assert.ErrorIs(failures[1], gdtjson.ErrJSONSchemaInvalid)
+	assert.ErrorContains(failures[1], "Does not match format 'isbn13'")
+	assert.ErrorIs(failures[2], gdtjson.ErrJSONSchemaInvalid)
+	assert.ErrorContains(failures[2], "Does not match format 'ulid'")
+}
+
+func TestJWT(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "jwt.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestJWTRejected(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	fp := filepath.Join("testdata", "jwt-rejected.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	failures := evalFailures(ctx, t, s)
+	require.Len(failures, 7)
+	for _, x := range []int{0, 1, 2} {
+		assert.ErrorIs(failures[x], api.ErrFailure)
+		assert.ErrorContains(failures[x], "JWT signature could not be verified")
+	}
+	assert.ErrorContains(failures[2], "unexpected signature algorithm \"none\"")
+	assert.ErrorIs(failures[3], api.ErrFailure)
+	assert.ErrorContains(failures[3], "expected JWT not to be expired")
+	assert.ErrorIs(failures[4], api.ErrNotIn)
+	assert.ErrorContains(failures[4], "expected JWT to grant scope books:admin")
+	assert.ErrorIs(failures[5], api.ErrNotIn)
+	assert.ErrorContains(failures[5], "expected JWT to contain claim email")
+	assert.ErrorIs(failures[6], api.ErrNotEqual)
+	assert.ErrorContains(failures[6], `expected JWT claim sub to be "admin"`)
+}
+
+func TestCookieAssertions(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "cookie-assertions.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestCookieAssertionFailures(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "cookie-assertion-failures.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	failures := evalFailures(ctx, t, s)
+	expErrs := []struct {
+		is  error
+		msg string
+	}{
+		{api.ErrNotIn, "expected HTTP response to set cookie no_such_cookie"},
+		{api.ErrNotEqual, `expected cookie remember_me to have value "bob" but got "alice"`},
+		{api.ErrNotEqual, `expected cookie session to have value matching "^[0-9]+$" but got `},
+		{api.ErrNotEqual, `expected cookie session to have Secure true but got "false"`},
+		{api.ErrNotEqual, `expected cookie remember_me to have HttpOnly false but got "true"`},
+		{api.ErrNotEqual, `expected cookie session to have SameSite strict but got "lax"`},
+		{api.ErrNotEqual, `expected cookie remember_me to have Path "/" but got "/login"`},
+		{api.ErrNotEqual, "expected cookie remember_me to have expiry >=8760h0m0s but got "},
+		{api.ErrNotEqual, "expected cookie remember_me to have no expiry but got "},
+		{api.ErrIn, "expected cookie session to be absent"},
+	}
+	require.Len(failures, len(expErrs))
+	for x, exp := range expErrs {
+		require.ErrorIs(failures[x], exp.is, s.Tests[x].Base().Title())
+		require.ErrorContains(failures[x], exp.msg)
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "redirects.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestRedirectFailures(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "redirect-failures.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	failures := evalFailures(ctx, t, s)
+	expErrs := []struct {
+		is  error
+		msg string
+	}{
+		{api.ErrNotEqual, "expected 2 redirects but got 3 [302 "},
+		{api.ErrNotEqual, "expected 0 redirects but got 3 [302 "},
+		{api.ErrNotEqual, `expected redirect 1 to have status 301 but got "302"`},
+		{api.ErrNotEqual, `expected redirect 2 to have Location "/sso/callback?code=xyz789&state=xyz" but got `},
+		{api.ErrNotEqual, `expected redirect 3 to have Location matching "^/authors$" but got "/books"`},
+	}
+	require.Len(failures, len(expErrs))
+	for x, exp := range expErrs {
+		require.ErrorIs(failures[x], exp.is, s.Tests[x].Base().Title())
+		require.ErrorContains(failures[x], exp.msg)
+	}
+}
+
+func TestRedirectsDefaults(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "redirects-defaults.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestTLSClientConfig(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "tls-client.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = gdtcontext.RegisterFixture(ctx, "books_api_mtls", newMTLSFixture())
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestTLSClientCertificateRequired(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "tls-no-client-cert.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = gdtcontext.RegisterFixture(ctx, "books_api_mtls", newMTLSFixture())
+
+	err = s.Run(ctx, t)
+	require.NotNil(err)
+}
+
+func TestTLSAssertions(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "tls-assertions.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = gdtcontext.RegisterFixture(ctx, "books_api_mtls", newMTLSFixture())
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestTLSFailures(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "tls-failures.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = gdtcontext.RegisterFixture(ctx, "books_api_mtls", newMTLSFixture())
+
+	failures := evalFailures(ctx, t, s)
+	expErrs := []struct {
+		is  error
+		msg string
+	}{
+		{api.ErrNotEqual, `expected TLS version 1.2 but got "1.3"`},
+		{api.ErrNotEqual, "expected TLS cipher suite TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 but got "},
+		{api.ErrNotEqual, `expected TLS certificate subject authors.internal but got "CN=books.internal,O=gdt-http"`},
+		{api.ErrNotEqual, `expected TLS certificate issuer CN=some other CA but got "CN=gdt-http test CA,O=gdt-http"`},
+		{api.ErrNotIn, "expected TLS certificate subject alternative names [books.internal books-api.internal] to contain authors.internal"},
+		{api.ErrNotEqual, "expected TLS certificate expiry >=100000 days but got "},
+	}
+	require.Len(failures, len(expErrs))
+	for x, exp := range expErrs {
+		require.ErrorIs(failures[x], exp.is, s.Tests[x].Base().Title())
+		require.ErrorContains(failures[x], exp.msg)
+	}
+}
+
+func TestHeaderAssertions(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "header-assertions.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestHeaderAssertionFailures(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "header-assertion-failures.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	failures := evalFailures(ctx, t, s)
+	expErrs := []struct {
+		is  error
+		msg string
+	}{
+		{api.ErrNotIn, "to contain X-Missing"},
+		{api.ErrNotEqual, `expected HTTP header X-Count to equal "41" but got "42"`},
+		{api.ErrIn, "expected HTTP header X-Powered-By to be absent but got [gdt]"},
+		{
+			api.ErrNotEqual,
+			`expected HTTP header X-Multi to match "^(one|two)$" but got "three"`,
+		},
+		{api.ErrNotEqual, `expected HTTP header X-Count < 42 but got "42"`},
+	}
+	require.Len(failures, len(expErrs))
+	for x, exp := range expErrs {
+		require.ErrorIs(failures[x], exp.is, s.Tests[x].Base().Title())
+		require.ErrorContains(failures[x], exp.msg)
+	}
+}
+
+func TestBodyAssertions(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "body-assertions.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestBodyAssertionFailures(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "body-assertion-failures.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	failures := evalFailures(ctx, t, s)
+	expErrs := []struct {
+		is  error
+		msg string
+	}{
+		{api.ErrNotIn, "expected HTTP body to contain For Whom the Bell Tolls"},
+		{api.ErrIn, "expected HTTP body not to contain Old Man and the Sea"},
+		{api.ErrNotEqual, `expected HTTP body to match "pages":\s*128\b`},
+		{api.ErrNotEqual, "-Forbidden\n+Unauthorized\n"},
+		{api.ErrNotEqual, "old-man-and-the-sea.json\n+++ response\n"},
+	}
+	require.Len(failures, len(expErrs))
+	for x, exp := range expErrs {
+		require.ErrorIs(failures[x], exp.is, s.Tests[x].Base().Title())
+		require.ErrorContains(failures[x], exp.msg)
+	}
+}
+
+func TestBodyNotEqualDiff(t *testing.T) {
+	assert := assert.New(t)
+
+	err := gdthttp.HTTPBodyNotEqual(
+		"golden.txt", "one\ntwo\nthree\n", "one\n2\nthree\n",
+	)
+	assert.ErrorIs(err, api.ErrNotEqual)
+	assert.ErrorContains(err, "--- golden.txt\n+++ response\n")
+	assert.ErrorContains(err, "-two\n+2\n")
+}
+
+func TestSnapshot(t *testing.T) {
+	require := require.New(t)
+
+	t.Setenv(gdthttp.EnvUpdateSnapshots, "")
+
+	fp := filepath.Join("testdata", "snapshot.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestSnapshotUpdate(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	gdthttp.SetUpdateSnapshots(true)
+	defer gdthttp.SetUpdateSnapshots(false)
+
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "snapshot-update.yaml")
+	err := os.WriteFile(fp, []byte(`
+name: snapshot-update
+fixtures:
+ - books_api
+tests:
+ - name: record a new snapshot
+   GET: /books/12ac1b94-5667-461e-80cb-ba8619cae61a
+   assert:
+     snapshot: snapshots/new.json
+ - name: rewrite a stale snapshot
+   GET: /authors
+   assert:
+     snapshot: snapshots/stale.json
+`), 0o644)
+	require.Nil(err)
+	stalePath := filepath.Join(dir, "snapshots", "stale.json")
+	require.Nil(os.MkdirAll(filepath.Dir(stalePath), 0o755))
+	require.Nil(os.WriteFile(stalePath, []byte("[]\n"), 0o644))
+
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+
+	got, err := os.ReadFile(filepath.Join(dir, "snapshots", "new.json"))
+	require.Nil(err)
+	assert.Contains(string(got), "\"title\": \"Old Man and the Sea\"")
+
+	got, err = os.ReadFile(stalePath)
+	require.Nil(err)
+	assert.Contains(string(got), "\"name\": \"Ernest Hemingway\"")
+}
@@ -5,14 +5,36 @@
 package http
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"strings"
+	"sync"
 
+	gdtjson "github.com/gdt-dev/core/assertion/json"
 	"github.com/google/uuid"
+	"github.com/theory/jsonpath"
 	gjs "github.com/xeipuuv/gojsonschema"
 )
 
+// FormatChecker determines whether a value has a particular format. Values
+// decoded from JSON are passed to IsFormat, so strings are `string`, numbers
+// are `float64` and so on.
+type FormatChecker interface {
+	IsFormat(input any) bool
+}
+
+// FormatCheckerFunc adapts an ordinary function to a FormatChecker.
+type FormatCheckerFunc func(input any) bool
+
+// IsFormat returns f(input).
+func (f FormatCheckerFunc) IsFormat(input any) bool {
+	return f(input)
+}
+
 var (
-	validators = map[string]gjs.FormatChecker{
+	validators = map[string]FormatChecker{
 		"date":                  gjs.DateFormatChecker{},
 		"time":                  gjs.TimeFormatChecker{},
 		"date-time":             gjs.DateTimeFormatChecker{},
@@ -31,20 +53,113 @@ var (
 		"json-pointer":          gjs.JSONPointerFormatChecker{},
 		"relative-json-pointer": gjs.RelativeJSONPointerFormatChecker{},
 		"uuid4":                 uuid4FormatChecker{},
+		"ulid":                  ulidFormatChecker{},
+		"semver":                semverFormatChecker{},
+		"iso8601-duration":      iso8601DurationFormatChecker{},
+		"base64":                base64FormatChecker{},
+		"jwt":                   jwtFormatChecker{},
 	}
+	// customFormats are the names of the formats registered with
+	// RegisterFormat.
+	customFormats = map[string]bool{}
+	// schemaFormatsAdded is true once the formats have been added to the
+	// gojsonschema format checkers.
+	schemaFormatsAdded bool
+	// validatorsMu protects validators, customFormats and schemaFormatsAdded.
+	validatorsMu sync.RWMutex
 )
 
+// addSchemaFormats makes the formats that gojsonschema does not know about,
+// and the formats registered with RegisterFormat, available to the `format`
+// keyword of JSON Schema documents.
+//
+// gojsonschema only supports a single, process-wide set of format checkers,
+// so adding the formats affects every user of gojsonschema in the test
+// binary. To avoid doing so in test binaries that merely import this package,
+// the formats are only added the first time a test spec with an
+// `assert.json.schema` assertion is evaluated.
+func addSchemaFormats() {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	if schemaFormatsAdded {
+		return
+	}
+	for name, c := range validators {
+		if customFormats[name] || !gjs.FormatCheckers.Has(name) {
+			gjs.FormatCheckers.Add(name, c)
+		}
+	}
+	schemaFormatsAdded = true
+}
+
+// RegisterFormat registers a FormatChecker for the named format. The format
+// may then be used in `assert.json.path_formats` and in the `format` keyword
+// of JSON Schema documents used in `assert.json.schema`. Registering a format
+// with the name of an existing format replaces the existing format.
+//
+// gojsonschema only supports a single, process-wide set of format checkers.
+// Once a test spec with an `assert.json.schema` assertion has been evaluated,
+// the format is therefore also available to, and replaces any format of the
+// same name used by, every other user of gojsonschema in the test binary.
+//
+// RegisterFormat is typically called from a test package's `init` or
+// `TestMain` function, before any test scenarios are run.
+func RegisterFormat(name string, checker FormatChecker) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators[name] = checker
+	customFormats[name] = true
+	if schemaFormatsAdded {
+		gjs.FormatCheckers.Add(name, checker)
+	}
+}
+
 // isFormatted takes a format string and a string value, determines the
 // validator function for that type of format string and returns whether the
 // value string is formatted correctly.
 func isFormatted(format string, input interface{}) (bool, error) {
+	validatorsMu.RLock()
 	c, ok := validators[format]
+	validatorsMu.RUnlock()
 	if !ok {
 		return false, fmt.Errorf("unknown format %s", format)
 	}
 	return c.IsFormat(input), nil
 }
 
+// checkPathFormats returns nil if the values found at the JSONPath expression
+// keys of the supplied map in the supplied JSON content have the format in the
+// corresponding map value, otherwise it returns the first failure.
+func checkPathFormats(pathFormats map[string]string, content []byte) error {
+	if len(pathFormats) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(content, &v); err != nil {
+		return gdtjson.JSONUnmarshalError(err, nil)
+	}
+	for path, format := range pathFormats {
+		p, err := jsonpath.Parse(path)
+		if err != nil {
+			// Not terminal because during parse we validate the JSONPath
+			// expression is valid.
+			return gdtjson.JSONPathNotFound(path, err)
+		}
+		nodes := p.Select(v)
+		if len(nodes) == 0 {
+			return gdtjson.JSONPathNotFound(path, nil)
+		}
+		ok, err := isFormatted(format, nodes[0])
+		if err != nil {
+			return gdtjson.JSONFormatError(format, err)
+		}
+		if !ok {
+			return gdtjson.JSONFormatNotEqual(path, format)
+		}
+	}
+	return nil
+}
+
 type uuid4FormatChecker struct{}
 
 func (c uuid4FormatChecker) IsFormat(input interface{}) bool {
@@ -58,3 +173,109 @@ func (c uuid4FormatChecker) IsFormat(input interface{}) bool {
 	}
 	return u.Version() == 4
 }
+
+// ulidRegex matches a ULID: 26 characters of Crockford's base32 where the
+// first character is at most 7 so the 128-bit value does not overflow.
+var ulidRegex = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Za-hjkmnp-tv-z]{25}$`)
+
+type ulidFormatChecker struct{}
+
+func (c ulidFormatChecker) IsFormat(input interface{}) bool {
+	s, ok := input.(string)
+	if !ok {
+		return false
+	}
+	return ulidRegex.MatchString(s)
+}
+
+// semverRegex is the regular expression suggested by https://semver.org.
+var semverRegex = regexp.MustCompile(
+	`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)` +
+		`(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+		`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`,
+)
+
+type semverFormatChecker struct{}
+
+func (c semverFormatChecker) IsFormat(input interface{}) bool {
+	s, ok := input.(string)
+	if !ok {
+		return false
+	}
+	return semverRegex.MatchString(s)
+}
+
+// iso8601DurationRegex matches an ISO 8601 duration such as `P1Y2M3DT4H5M6S`
+// or `P2W`. Whether at least one component is present is checked separately.
+var iso8601DurationRegex = regexp.MustCompile(
+	`^P(?:\d+(?:[.,]\d+)?Y)?(?:\d+(?:[.,]\d+)?M)?(?:\d+(?:[.,]\d+)?W)?` +
+		`(?:\d+(?:[.,]\d+)?D)?` +
+		`(?:T(?:\d+(?:[.,]\d+)?H)?(?:\d+(?:[.,]\d+)?M)?(?:\d+(?:[.,]\d+)?S)?)?$`,
+)
+
+type iso8601DurationFormatChecker struct{}
+
+func (c iso8601DurationFormatChecker) IsFormat(input interface{}) bool {
+	s, ok := input.(string)
+	if !ok {
+		return false
+	}
+	if s == "P" || strings.HasSuffix(s, "T") {
+		return false
+	}
+	return iso8601DurationRegex.MatchString(s)
+}
+
+type base64FormatChecker struct{}
+
+func (c base64FormatChecker) IsFormat(input interface{}) bool {
+	s, ok := input.(string)
+	if !ok {
+		return false
+	}
+	_, err := base64.StdEncoding.Strict().DecodeString(s)
+	return err == nil
+}
+
+// jwtFormatChecker checks for a JSON Web Token in the JWS compact
+// serialization: a base64url-encoded JSON header containing an `alg`, a
+// base64url-encoded JSON payload and a possibly empty base64url-encoded
+// signature, separated by dots. The signature is not verified.
+type jwtFormatChecker struct{}
+
+func (c jwtFormatChecker) IsFormat(input interface{}) bool {
+	s, ok := input.(string)
+	if !ok {
+		return false
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return false
+	}
+	if header.Alg == "" {
+		return false
+	}
+	var claims map[string]any
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return false
+	}
+	_, err := base64.RawURLEncoding.DecodeString(parts[2])
+	return err == nil
+}
+
+// decodeJWTSegment decodes the supplied base64url-encoded JSON segment of a
+// JSON Web Token into the supplied value.
+func decodeJWTSegment(segment string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,118 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+package http
+
+import (
+	"fmt"
+	nethttp "net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// HeaderExpect describes an assertion about an HTTP header in the response.
+// With only a Name, the assertion is that the header is present.
+type HeaderExpect struct {
+	// Name is the name of the HTTP header. It is canonicalized, so matching
+	// is case-insensitive.
+	Name string `yaml:"name"`
+	// Value is the expected value of the HTTP header. Comparison is
+	// case-insensitive.
+	Value string `yaml:"value,omitempty"`
+	// Matches is a regular expression the value of the HTTP header must
+	// match.
+	Matches string `yaml:"matches,omitempty"`
+	// Absent, if true, asserts that the HTTP header is not in the response.
+	Absent bool `yaml:"absent,omitempty"`
+	// All, if true, asserts on every value of a repeated HTTP header instead
+	// of only the first value.
+	All bool `yaml:"all,omitempty"`
+	// LT asserts that the numeric value of the HTTP header is less than the
+	// supplied number.
+	LT *float64 `yaml:"lt,omitempty"`
+	// LTE asserts that the numeric value of the HTTP header is less than or
+	// equal to the supplied number.
+	LTE *float64 `yaml:"lte,omitempty"`
+	// GT asserts that the numeric value of the HTTP header is greater than
+	// the supplied number.
+	GT *float64 `yaml:"gt,omitempty"`
+	// GTE asserts that the numeric value of the HTTP header is greater than
+	// or equal to the supplied number.
+	GTE *float64 `yaml:"gte,omitempty"`
+	// re is the compiled Matches regular expression.
+	re *regexp.Regexp
+}
+
+// String returns the assertion in the `Key: Value` string form when it only
+// compares the value, otherwise just the name of the HTTP header.
+func (h *HeaderExpect) String() string {
+	if h.Value != "" {
+		return h.Name + ": " + h.Value
+	}
+	return h.Name
+}
+
+// check returns nil if the supplied HTTP response headers satisfy the
+// assertion, otherwise it returns the failure.
+func (h *HeaderExpect) check(headers nethttp.Header) error {
+	vals := headers.Values(h.Name)
+	if h.Absent {
+		if len(vals) > 0 {
+			return HTTPHeaderPresent(h.Name, vals)
+		}
+		return nil
+	}
+	if len(vals) == 0 {
+		return HTTPHeaderNotIn(h, headers)
+	}
+	if !h.All {
+		vals = vals[:1]
+	}
+	for _, val := range vals {
+		val = strings.TrimSpace(val)
+		if h.Value != "" && !strings.EqualFold(h.Value, val) {
+			return HTTPHeaderNotMatched(
+				h.Name, fmt.Sprintf("to equal %q", h.Value), val,
+			)
+		}
+		if h.re != nil && !h.re.MatchString(val) {
+			return HTTPHeaderNotMatched(
+				h.Name, fmt.Sprintf("to match %q", h.Matches), val,
+			)
+		}
+		if err := h.compare(val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compare returns nil if the supplied HTTP header value satisfies all of the
+// numeric comparisons, otherwise it returns the failure.
+func (h *HeaderExpect) compare(val string) error {
+	comparisons := []struct {
+		op    string
+		bound *float64
+		ok    func(float64, float64) bool
+	}{
+		{"<", h.LT, func(a, b float64) bool { return a < b }},
+		{"<=", h.LTE, func(a, b float64) bool { return a <= b }},
+		{">", h.GT, func(a, b float64) bool { return a > b }},
+		{">=", h.GTE, func(a, b float64) bool { return a >= b }},
+	}
+	for _, c := range comparisons {
+		if c.bound == nil {
+			continue
+		}
+		cond := fmt.Sprintf(
+			"%s %s", c.op, strconv.FormatFloat(*c.bound, 'f', -1, 64),
+		)
+		num, err := strconv.ParseFloat(val, 64)
+		if err != nil || !c.ok(num, *c.bound) {
+			return HTTPHeaderNotMatched(h.Name, cond, val)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// HTMLExpect contains an assertion about the elements of an HTML response
+// that are selected by a CSS selector.
+type HTMLExpect struct {
+	// Selector is the CSS selector that selects the elements the assertion
+	// is about. When no other condition is specified, at least one element
+	// must be selected.
+	Selector string `yaml:"selector"`
+	// Count is the number of elements the selector should select.
+	Count *int `yaml:"count,omitempty"`
+	// Text is the expected text of the first selected element, ignoring
+	// leading and trailing whitespace.
+	Text *string `yaml:"text,omitempty"`
+	// Contains is a list of strings that should be present in the text of
+	// the first selected element.
+	Contains []string `yaml:"contains,omitempty"`
+	// Attrs is a map, keyed by attribute name, of the expected attribute
+	// values of the first selected element.
+	Attrs map[string]string `yaml:"attrs,omitempty"`
+	// sel is the compiled Selector.
+	sel cascadia.Selector
+}
+
+// check returns nil if the elements of the supplied HTML document satisfy the
+// assertion, otherwise it returns a failure.
+func (e *HTMLExpect) check(doc *goquery.Document) error {
+	found := doc.FindMatcher(e.sel)
+	if e.Count != nil {
+		if found.Length() != *e.Count {
+			return HTTPSelectorNotMatched(
+				e.Selector,
+				fmt.Sprintf("count %d", *e.Count),
+				fmt.Sprintf("%d", found.Length()),
+			)
+		}
+	} else if found.Length() == 0 {
+		return HTTPSelectorNotFound(e.Selector)
+	}
+	if found.Length() == 0 {
+		return nil
+	}
+	first := found.First()
+	text := strings.TrimSpace(first.Text())
+	if e.Text != nil && text != *e.Text {
+		return HTTPSelectorNotMatched(
+			e.Selector, fmt.Sprintf("text %q", *e.Text), text,
+		)
+	}
+	for _, s := range e.Contains {
+		if !strings.Contains(text, s) {
+			return HTTPSelectorNotMatched(
+				e.Selector, fmt.Sprintf("text containing %q", s), text,
+			)
+		}
+	}
+	for attr, exp := range e.Attrs {
+		got, ok := first.Attr(attr)
+		if !ok {
+			return HTTPSelectorNotMatched(
+				e.Selector, fmt.Sprintf("attribute %s", attr), "no attribute",
+			)
+		}
+		if got != exp {
+			return HTTPSelectorNotMatched(
+				e.Selector, fmt.Sprintf("%s=%q", attr, exp), got,
+			)
+		}
+	}
+	return nil
+}
+
+// parseHTML parses the supplied response content as an HTML document.
+func parseHTML(b []byte) (*goquery.Document, error) {
+	return goquery.NewDocumentFromReader(bytes.NewReader(b))
+}
+
+// selectHTML returns the text of the first element in the supplied HTML
+// document selected by the supplied CSS selector, or the value of the
+// element's named attribute if attr is not empty. The returned bool is false
+// if no element was selected or the element does not have the attribute.
+func selectHTML(
+	doc *goquery.Document,
+	sel cascadia.Selector,
+	attr string,
+) (string, bool) {
+	found := doc.FindMatcher(sel)
+	if found.Length() == 0 {
+		return "", false
+	}
+	first := found.First()
+	if attr != "" {
+		return first.Attr(attr)
+	}
+	return strings.TrimSpace(first.Text()), true
+}
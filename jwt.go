@@ -0,0 +1,352 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gdt-dev/core/debug"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/theory/jsonpath"
+)
+
+// jwtSignatureAlgorithms are the signature algorithms a JWT may be signed
+// with. The algorithm `none` is never accepted.
+var jwtSignatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.EdDSA,
+	jose.HS256, jose.HS384, jose.HS512,
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.PS256, jose.PS384, jose.PS512,
+}
+
+// JWTExpect contains assertions about a JSON Web Token in an HTTP response.
+// Exactly one of From, Header or Cookie should be set to locate the JWT.
+type JWTExpect struct {
+	// From is a JSONPath expression that locates the JWT in the response
+	// content.
+	From string `yaml:"from,omitempty"`
+	// Header is the name of an HTTP header in the response containing the
+	// JWT. A leading `Bearer ` is removed from the header's value.
+	Header string `yaml:"header,omitempty"`
+	// Cookie is the name of a cookie set by the response containing the JWT.
+	Cookie string `yaml:"cookie,omitempty"`
+	// KeyFile is the path to a file containing the key the JWT's signature
+	// is verified with: a PEM-encoded public key or certificate, a JSON Web
+	// Key, a JSON Web Key Set or, for HMAC signatures, the shared secret.
+	// Relative paths are resolved relative to the test scenario file.
+	KeyFile string `yaml:"key_file,omitempty"`
+	// JWKSURL is the URL of a JSON Web Key Set containing the key the JWT's
+	// signature is verified with. URLs beginning with `/` are relative to
+	// the base URL.
+	JWKSURL string `yaml:"jwks_url,omitempty"`
+	// TokenHeader is a map, keyed by JOSE header name, of the expected values
+	// of the JWT's header, e.g. `alg: ES256`.
+	TokenHeader map[string]string `yaml:"token_header,omitempty"`
+	// Claims is a map, keyed by claim name, of the expected values of the
+	// JWT's claims. Claims that are not strings are compared with their JSON
+	// form, except that a claim that is a list matches if any of its
+	// elements matches. Variable references in the expected values are
+	// interpolated.
+	Claims map[string]string `yaml:"claims,omitempty"`
+	// NotExpired indicates that the JWT should have an `exp` claim in the
+	// future and, if it has an `nbf` claim, that it is not in the future.
+	NotExpired bool `yaml:"not_expired,omitempty"`
+	// Scopes is a list of scopes that the JWT should grant in its
+	// space-separated `scope` claim or its `scp` claim.
+	Scopes []string `yaml:"scopes,omitempty"`
+	// key is the key read from KeyFile.
+	key any
+}
+
+// source returns a description of where the JWT is located.
+func (e *JWTExpect) source() string {
+	switch {
+	case e.Header != "":
+		return fmt.Sprintf("HTTP header %s", e.Header)
+	case e.Cookie != "":
+		return fmt.Sprintf("cookie %s", e.Cookie)
+	}
+	return fmt.Sprintf("JSONPath %s", e.From)
+}
+
+// verificationKey returns the key that the JWT's signature should be verified
+// with, or nil if the signature should not be verified. A JSON Web Key Set is
+// requested from the JWKSURL each time the key is needed so that rotated keys
+// are picked up.
+func (e *JWTExpect) verificationKey(
+	ctx context.Context,
+	c *nethttp.Client,
+	defaults *Defaults,
+) (any, error) {
+	if e == nil {
+		return nil, nil
+	}
+	if e.JWKSURL == "" {
+		return e.key, nil
+	}
+	jwksURL, err := expandString(ctx, e.JWKSURL)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(jwksURL, "/") {
+		jwksURL = defaults.BaseURLFromContext(ctx) + jwksURL
+	}
+	req, err := nethttp.NewRequest("GET", jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	debug.Printf(ctx, "http: > GET %s (jwks request)", jwksURL)
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, JWKSRequestFailed(err.Error())
+	}
+	defer resp.Body.Close() // nolint:errcheck
+	debug.Printf(ctx, "http: < %d (jwks response)", resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, JWKSRequestFailed(err.Error())
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, JWKSRequestFailed(
+			fmt.Sprintf("JWKS endpoint returned %d", resp.StatusCode),
+		)
+	}
+	var jwks jose.JSONWebKeySet
+	if err := json.Unmarshal(body, &jwks); err != nil {
+		return nil, JWKSRequestFailed(err.Error())
+	}
+	return &jwks, nil
+}
+
+// check returns nil if the JWT located in the supplied response satisfies the
+// assertions, otherwise it returns the first failure. If key is not nil, the
+// JWT's signature is verified with it.
+func (e *JWTExpect) check(
+	ctx context.Context,
+	r *nethttp.Response,
+	b []byte,
+	format string,
+	key any,
+) error {
+	token, found := e.locate(r, b, format)
+	if !found {
+		return HTTPJWTNotFound(e.source())
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return HTTPJWTInvalid(
+			fmt.Errorf("expected 3 segments but got %d", len(parts)),
+		)
+	}
+	header := map[string]any{}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return HTTPJWTInvalid(fmt.Errorf("invalid header: %w", err))
+	}
+	claims := map[string]any{}
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return HTTPJWTInvalid(fmt.Errorf("invalid claims: %w", err))
+	}
+	if key != nil {
+		tok, err := jwt.ParseSigned(token, jwtSignatureAlgorithms)
+		if err != nil {
+			return HTTPJWTSignatureInvalid(err)
+		}
+		if err := tok.Claims(key); err != nil {
+			return HTTPJWTSignatureInvalid(err)
+		}
+	}
+	for name, exp := range e.TokenHeader {
+		got, ok := header[name]
+		if !ok {
+			return HTTPJWTHeaderNotEqual(name, exp, "")
+		}
+		if !jwtValueMatches(got, exp) {
+			return HTTPJWTHeaderNotEqual(name, exp, varString(got))
+		}
+	}
+	for name, exp := range e.Claims {
+		exp, err := expandString(ctx, exp)
+		if err != nil {
+			return err
+		}
+		got, ok := claims[name]
+		if !ok {
+			return HTTPJWTClaimNotFound(name)
+		}
+		if !jwtValueMatches(got, exp) {
+			return HTTPJWTClaimNotEqual(name, exp, varString(got))
+		}
+	}
+	if e.NotExpired {
+		if err := checkJWTTimes(claims, time.Now()); err != nil {
+			return err
+		}
+	}
+	if len(e.Scopes) > 0 {
+		granted := jwtScopes(claims)
+		for _, scope := range e.Scopes {
+			if !slices.Contains(granted, scope) {
+				return HTTPJWTScopeNotGranted(scope, granted)
+			}
+		}
+	}
+	return nil
+}
+
+// locate returns the JWT in the supplied response and whether it was found.
+func (e *JWTExpect) locate(
+	r *nethttp.Response,
+	b []byte,
+	format string,
+) (string, bool) {
+	switch {
+	case e.Header != "":
+		val := strings.TrimSpace(r.Header.Get(e.Header))
+		if len(val) > 7 && strings.EqualFold(val[:7], "bearer ") {
+			val = strings.TrimSpace(val[7:])
+		}
+		return val, val != ""
+	case e.Cookie != "":
+		for _, c := range r.Cookies() {
+			if c.Name == e.Cookie {
+				return c.Value, c.Value != ""
+			}
+		}
+		return "", false
+	}
+	doc, err := decodeBody(format, b)
+	if err != nil {
+		return "", false
+	}
+	p, err := jsonpath.Parse(e.From)
+	if err != nil {
+		// Not terminal because during parse we validate the JSONPath
+		// expression is valid.
+		return "", false
+	}
+	nodes := p.Select(doc)
+	if len(nodes) == 0 {
+		return "", false
+	}
+	token, ok := nodes[0].(string)
+	return token, ok && token != ""
+}
+
+// jwtValueMatches returns true if the supplied value of a JWT header or claim
+// matches the expected string. A list matches if any of its elements match.
+func jwtValueMatches(got any, exp string) bool {
+	if list, ok := got.([]any); ok {
+		for _, item := range list {
+			if varString(item) == exp {
+				return true
+			}
+		}
+	}
+	return varString(got) == exp
+}
+
+// checkJWTTimes returns nil if the supplied JWT claims have an `exp` claim
+// after now and any `nbf` claim is not after now, otherwise it returns a
+// failure.
+func checkJWTTimes(claims map[string]any, now time.Time) error {
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return HTTPJWTExpired("JWT has no exp claim")
+	}
+	expiry := time.Unix(int64(exp), 0)
+	if !now.Before(expiry) {
+		return HTTPJWTExpired(
+			fmt.Sprintf("JWT expired at %s", expiry.UTC().Format(time.RFC3339)),
+		)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok {
+		notBefore := time.Unix(int64(nbf), 0)
+		if now.Before(notBefore) {
+			return HTTPJWTExpired(fmt.Sprintf(
+				"JWT not valid before %s",
+				notBefore.UTC().Format(time.RFC3339),
+			))
+		}
+	}
+	return nil
+}
+
+// jwtScopes returns the scopes granted by the supplied JWT claims in the
+// space-separated `scope` claim or the `scp` claim, which may be a list or a
+// space-separated string.
+func jwtScopes(claims map[string]any) []string {
+	scopes := []string{}
+	if s, ok := claims["scope"].(string); ok {
+		scopes = append(scopes, strings.Fields(s)...)
+	}
+	switch scp := claims["scp"].(type) {
+	case string:
+		scopes = append(scopes, strings.Fields(scp)...)
+	case []any:
+		for _, s := range scp {
+			if s, ok := s.(string); ok {
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	return scopes
+}
+
+// readJWTKey reads the key that JWT signatures are verified with from the
+// supplied file. The file may contain a PEM-encoded public key or
+// certificate, a JSON Web Key, a JSON Web Key Set or an HMAC secret.
+func readJWTKey(path string) (any, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(contents); block != nil {
+		switch block.Type {
+		case "PUBLIC KEY":
+			return x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			return x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			return cert.PublicKey, nil
+		}
+		return nil, fmt.Errorf("unsupported PEM block %s", block.Type)
+	}
+	trimmed := bytes.TrimSpace(contents)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var jwks jose.JSONWebKeySet
+		if err := json.Unmarshal(trimmed, &jwks); err == nil && len(jwks.Keys) > 0 {
+			return &jwks, nil
+		}
+		var jwk jose.JSONWebKey
+		if err := json.Unmarshal(trimmed, &jwk); err != nil {
+			return nil, err
+		}
+		return &jwk, nil
+	}
+	secret := bytes.TrimRight(contents, "\r\n")
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("key file is empty")
+	}
+	return secret, nil
+}
@@ -0,0 +1,170 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdt-dev/core/debug"
+)
+
+// oauth2ExpiryDelta is how long before its expiry a cached access token is
+// considered expired, which avoids sending a token that expires in flight.
+const oauth2ExpiryDelta = 10 * time.Second
+
+// OAuth2Auth describes how to obtain an access token using the OAuth2 client
+// credentials grant. The access token is sent in the Authorization header as
+// a bearer token.
+//
+// The access token is cached and reused by all test specs in the scenario
+// until it expires. If the server responds to a request with a 401
+// Unauthorized, the cached access token is discarded and the request is sent
+// once more with a new access token.
+type OAuth2Auth struct {
+	// TokenURL is the URL of the token endpoint. If the URL is a path, it is
+	// relative to the base URL.
+	TokenURL string `yaml:"token_url"`
+	// ClientID is the client identifier.
+	ClientID string `yaml:"client_id"`
+	// ClientSecret is the client secret.
+	ClientSecret string `yaml:"client_secret,omitempty"`
+	// Scopes is the list of scopes to request.
+	Scopes []string `yaml:"scopes,omitempty"`
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// oauth2TokenResponse is the successful response of a token endpoint.
+type oauth2TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// oauth2ErrorResponse is the error response of a token endpoint.
+type oauth2ErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// accessToken returns the cached access token, requesting a new access token
+// from the token endpoint if there is no cached access token or the cached
+// access token has expired.
+func (o *OAuth2Auth) accessToken(
+	ctx context.Context,
+	defaults *Defaults,
+) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.token != "" {
+		if o.expiry.IsZero() ||
+			time.Now().Add(oauth2ExpiryDelta).Before(o.expiry) {
+			return o.token, nil
+		}
+	}
+	tr, err := o.requestToken(ctx, tokenClient(ctx, defaults), defaults)
+	if err != nil {
+		return "", err
+	}
+	o.token = tr.AccessToken
+	o.expiry = time.Time{}
+	if tr.ExpiresIn > 0 {
+		o.expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return o.token, nil
+}
+
+// invalidate discards the cached access token.
+func (o *OAuth2Auth) invalidate() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.token = ""
+	o.expiry = time.Time{}
+}
+
+// requestToken requests a new access token from the token endpoint.
+func (o *OAuth2Auth) requestToken(
+	ctx context.Context,
+	c *nethttp.Client,
+	defaults *Defaults,
+) (*oauth2TokenResponse, error) {
+	tokenURL, err := expandString(ctx, o.TokenURL)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(tokenURL, "/") {
+		tokenURL = defaults.BaseURLFromContext(ctx) + tokenURL
+	}
+	clientID, err := expandString(ctx, o.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	clientSecret, err := expandString(ctx, o.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	form := neturl.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(o.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.Scopes, " "))
+	}
+	req, err := nethttp.NewRequest(
+		"POST", tokenURL, strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// RFC 6749 section 2.3.1 requires the client credentials to be
+	// form-urlencoded before they are used as the Basic authentication
+	// username and password.
+	req.SetBasicAuth(
+		neturl.QueryEscape(clientID), neturl.QueryEscape(clientSecret),
+	)
+
+	debug.Printf(ctx, "http: > POST %s (oauth2 token request)", tokenURL)
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, OAuth2TokenRequestFailed(err.Error())
+	}
+	defer resp.Body.Close() // nolint:errcheck
+	debug.Printf(ctx, "http: < %d (oauth2 token response)", resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, OAuth2TokenRequestFailed(err.Error())
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		reason := fmt.Sprintf("token endpoint returned %d", resp.StatusCode)
+		var er oauth2ErrorResponse
+		if json.Unmarshal(body, &er) == nil && er.Error != "" {
+			reason += ": " + er.Error
+			if er.ErrorDescription != "" {
+				reason += ": " + er.ErrorDescription
+			}
+		}
+		return nil, OAuth2TokenRequestFailed(reason)
+	}
+	var tr oauth2TokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return nil, OAuth2TokenRequestFailed(err.Error())
+	}
+	if tr.AccessToken == "" {
+		return nil, OAuth2TokenRequestFailed(
+			"token endpoint response is missing access_token",
+		)
+	}
+	return &tr, nil
+}
@@ -6,8 +6,15 @@ package http
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
 
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/xpath"
 	"github.com/gdt-dev/core/api"
 	gdtjson "github.com/gdt-dev/core/assertion/json"
 	"github.com/gdt-dev/core/parse"
@@ -19,20 +26,95 @@ import (
 var validHTTPMethods = []string{
 	"DELETE",
 	"GET",
+	"HEAD",
+	"OPTIONS",
 	"PATCH",
 	"POST",
 	"PUT",
+	"TRACE",
+}
+
+// idempotentHTTPMethods are the HTTP methods for which sending the same
+// request more than once has the same effect on the server as sending it
+// once, and which may therefore be retried. They are the safe methods, which
+// do not modify server state, plus PUT and DELETE. See RFC 9110 Sections
+// 9.2.1 and 9.2.2.
+var idempotentHTTPMethods = []string{
+	"DELETE",
+	"GET",
+	"HEAD",
+	"OPTIONS",
+	"PUT",
+	"TRACE",
+}
+
+var (
+	extensionMethodsMu sync.RWMutex
+	// extensionMethods are the HTTP extension methods registered with
+	// RegisterMethod.
+	extensionMethods = []string{}
+)
+
+// httpTokenRegex matches an HTTP token, which is the syntax of HTTP method
+// names. See RFC 9110 Section 5.6.2.
+var httpTokenRegex = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// RegisterMethod allows test specs to use the supplied HTTP extension
+// methods, for example the WebDAV `PROPFIND` method. Test specs may only use
+// the standard HTTP methods unless extension methods are registered before the
+// test scenarios are parsed. Extension methods are case-sensitive and are
+// never retried by default.
+//
+// An error is returned, and none of the methods are registered, if any of
+// the methods is not a valid HTTP method name.
+func RegisterMethod(methods ...string) error {
+	for _, method := range methods {
+		if !httpTokenRegex.MatchString(method) {
+			return fmt.Errorf("invalid HTTP method: %q", method)
+		}
+	}
+	extensionMethodsMu.Lock()
+	defer extensionMethodsMu.Unlock()
+	for _, method := range methods {
+		if !lo.Contains(extensionMethods, method) {
+			extensionMethods = append(extensionMethods, method)
+		}
+	}
+	return nil
+}
+
+// parseMethod returns the HTTP method in the supplied method field value.
+// Standard HTTP methods are case-insensitive. Extension methods must have
+// been registered with RegisterMethod.
+func parseMethod(node *yaml.Node) (string, error) {
+	if node.Kind != yaml.ScalarNode {
+		return "", parse.ExpectedScalarAt(node)
+	}
+	method := strings.TrimSpace(node.Value)
+	if upper := strings.ToUpper(method); lo.Contains(validHTTPMethods, upper) {
+		return upper, nil
+	}
+	extensionMethodsMu.RLock()
+	defer extensionMethodsMu.RUnlock()
+	if lo.Contains(extensionMethods, method) {
+		return method, nil
+	}
+	return "", InvalidHTTPMethodAt(node.Value, node)
 }
 
 // InvalidHTTPMethodAt returns a parse error indicating the test author used an
 // invalid method field value.
 func InvalidHTTPMethodAt(method string, node *yaml.Node) error {
+	extensionMethodsMu.RLock()
+	valid := slices.Concat(validHTTPMethods, extensionMethods)
+	extensionMethodsMu.RUnlock()
 	return &parse.Error{
 		Line:   node.Line,
 		Column: node.Column,
 		Message: fmt.Sprintf(
-			"invalid HTTP method specified: %s. valid values: %s",
-			method, strings.Join(validHTTPMethods, ","),
+			"invalid HTTP method specified: %s. valid values: %s. "+
+				"use http.RegisterMethod to allow extension methods",
+			method, strings.Join(valid, ","),
 		),
 	}
 }
@@ -49,6 +131,21 @@ func EitherShortcutOrHTTPSpecAt(node *yaml.Node) error {
 	}
 }
 
+// RequestFieldWithoutMethodAt returns a parse error indicating the test
+// author specified a request field such as `headers` at the top level of a
+// test spec that has no HTTP method.
+func RequestFieldWithoutMethodAt(field string, node *yaml.Node) error {
+	return &parse.Error{
+		Line:   node.Line,
+		Column: node.Column,
+		Message: fmt.Sprintf(
+			"request field %s requires a method. specify the HTTP method "+
+				"with `method` or a shortcut (e.g. `GET`)",
+			field,
+		),
+	}
+}
+
 // MultipleHTTPMethods returns a parse error indicating the test author
 // specified multiple HTTP methods either full-form or via shortcuts.
 func MultipleHTTPMethods(
@@ -67,6 +164,245 @@ func MultipleHTTPMethods(
 	}
 }
 
+// MultipleRequestBodies returns a parse error indicating the test author
+// specified more than one request payload, e.g. both `data` and `body`.
+func MultipleRequestBodies(
+	firstField string,
+	secondField string,
+	node *yaml.Node,
+) error {
+	return &parse.Error{
+		Line:   node.Line,
+		Column: node.Column,
+		Message: fmt.Sprintf(
+			"multiple request payloads specified (%q, %q). "+
+				"please specify a single request payload for each test spec.",
+			firstField, secondField,
+		),
+	}
+}
+
+// InvalidBodyModeAt returns a parse error indicating the test author did not
+// specify exactly one of the valid body modes in a `body` field.
+func InvalidBodyModeAt(node *yaml.Node) error {
+	return &parse.Error{
+		Line:   node.Line,
+		Column: node.Column,
+		Message: fmt.Sprintf(
+			"body must specify exactly one of: %s",
+			strings.Join(validBodyModes, ","),
+		),
+	}
+}
+
+// InvalidMultipartPartAt returns a parse error indicating the test author
+// specified an invalid part in a `body.multipart` field.
+func InvalidMultipartPartAt(reason string, node *yaml.Node) error {
+	return &parse.Error{
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf("invalid multipart part: %s", reason),
+	}
+}
+
+// InvalidAuthAt returns a parse error indicating the test author specified an
+// invalid `auth` field.
+func InvalidAuthAt(reason string, node *yaml.Node) error {
+	return &parse.Error{
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf("invalid auth: %s", reason),
+	}
+}
+
+// InvalidStatusAt returns a parse error indicating the test author specified
+// an invalid expected HTTP status.
+func InvalidStatusAt(status string, node *yaml.Node) error {
+	return &parse.Error{
+		Line:   node.Line,
+		Column: node.Column,
+		Message: fmt.Sprintf(
+			"invalid HTTP status specified: %s. expected an HTTP status "+
+				"code (200), class (2xx) or range (200-299) between %d "+
+				"and %d",
+			status, minStatusCode, maxStatusCode,
+		),
+	}
+}
+
+// InvalidHeaderExpectAt returns a parse error indicating the test author
+// specified an invalid HTTP header assertion.
+func InvalidHeaderExpectAt(reason string, node *yaml.Node) error {
+	return &parse.Error{
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf("invalid header assertion: %s", reason),
+	}
+}
+
+// InvalidCookieExpectAt returns a parse error indicating the test author
+// specified an invalid cookie assertion.
+func InvalidCookieExpectAt(reason string, node *yaml.Node) error {
+	return &parse.Error{
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf("invalid cookie assertion: %s", reason),
+	}
+}
+
+// InvalidFollowRedirectsAt returns a parse error indicating the test author
+// specified an invalid `follow_redirects` field.
+func InvalidFollowRedirectsAt(node *yaml.Node) error {
+	return &parse.Error{
+		Line:   node.Line,
+		Column: node.Column,
+		Message: fmt.Sprintf(
+			"invalid follow_redirects: %s. expected true, false or a "+
+				"maximum number of redirects",
+			node.Value,
+		),
+	}
+}
+
+// InvalidTLSAt returns a parse error indicating the test author specified an
+// invalid `tls` field.
+func InvalidTLSAt(reason string, node *yaml.Node) error {
+	return &parse.Error{
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf("invalid tls: %s", reason),
+	}
+}
+
+// InvalidHTMLExpectAt returns a parse error indicating the test author
+// specified an invalid HTML assertion.
+func InvalidHTMLExpectAt(reason string, node *yaml.Node) error {
+	return &parse.Error{
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf("invalid HTML assertion: %s", reason),
+	}
+}
+
+// InvalidJWTExpectAt returns a parse error indicating the test author
+// specified an invalid JWT assertion.
+func InvalidJWTExpectAt(reason string, node *yaml.Node) error {
+	return &parse.Error{
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf("invalid JWT assertion: %s", reason),
+	}
+}
+
+// InvalidBodyFormatAt returns a parse error indicating the test author
+// specified an unknown format for the response content.
+func InvalidBodyFormatAt(format string, node *yaml.Node) error {
+	return &parse.Error{
+		Line:   node.Line,
+		Column: node.Column,
+		Message: fmt.Sprintf(
+			"invalid format %q. valid formats: %s",
+			format, strings.Join(validBodyFormats, ", "),
+		),
+	}
+}
+
+// InvalidSnapshotAt returns a parse error indicating the test author
+// specified an invalid snapshot assertion.
+func InvalidSnapshotAt(reason string, node *yaml.Node) error {
+	return &parse.Error{
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf("invalid snapshot assertion: %s", reason),
+	}
+}
+
+// InvalidLatencyAt returns a parse error indicating the test author
+// specified an invalid latency assertion or variable source.
+func InvalidLatencyAt(reason string, node *yaml.Node) error {
+	return &parse.Error{
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf("invalid latency: %s", reason),
+	}
+}
+
+// InvalidVarSourceAt returns a parse error indicating the test author did not
+// specify exactly one source for a variable's value.
+func InvalidVarSourceAt(node *yaml.Node) error {
+	return &parse.Error{
+		Line:   node.Line,
+		Column: node.Column,
+		Message: "var must specify exactly one of: " +
+			"from, header, status, latency, cookie, regex, xpath, " +
+			"selector. all may only be specified with from, " +
+			"group may only be specified with regex and " +
+			"attr may only be specified with selector",
+	}
+}
+
+// InvalidRegexAt returns a parse error indicating the test author specified a
+// regular expression that could not be compiled.
+func InvalidRegexAt(expr string, err error, node *yaml.Node) error {
+	return &parse.Error{
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf("invalid regular expression %s: %s", expr, err),
+	}
+}
+
+// InvalidXPathAt returns a parse error indicating the test author specified
+// an XPath expression that could not be compiled.
+func InvalidXPathAt(expr string, err error, node *yaml.Node) error {
+	return &parse.Error{
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf("invalid XPath expression %s: %s", expr, err),
+	}
+}
+
+// InvalidSelectorAt returns a parse error indicating the test author
+// specified a CSS selector that could not be compiled.
+func InvalidSelectorAt(selector string, err error, node *yaml.Node) error {
+	return &parse.Error{
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf("invalid CSS selector %s: %s", selector, err),
+	}
+}
+
+// XSDNotSupportedAt returns a parse error indicating the test author
+// specified an XSD document to validate XML content against but gdt-http was
+// built without the `xsd` build tag.
+func XSDNotSupportedAt(node *yaml.Node) error {
+	return &parse.Error{
+		Line:   node.Line,
+		Column: node.Column,
+		Message: "XSD validation requires building with the `xsd` " +
+			"build tag (go test -tags xsd) and libxml2",
+	}
+}
+
+// InvalidRegexGroupAt returns a parse error indicating the test author
+// specified a capture group that does not exist in a regular expression.
+func InvalidRegexGroupAt(group string, expr string, node *yaml.Node) error {
+	return &parse.Error{
+		Line:   node.Line,
+		Column: node.Column,
+		Message: fmt.Sprintf(
+			"capture group %s does not exist in regular expression %s",
+			group, expr,
+		),
+	}
+}
+
+// requestFields are the fields of a test spec's HTTP request that may be
+// specified at the top level of the test spec alongside a method shortcut.
+var requestFields = []string{
+	"data", "headers", "query", "body", "data_file", "data-file",
+	"body_file", "body-file", "auth", "cookies", "follow_redirects",
+}
+
 func (s *Spec) UnmarshalYAML(node *yaml.Node) error {
 	if node.Kind != yaml.MappingNode {
 		return parse.ExpectedMapAt(node)
@@ -91,15 +427,14 @@ func (s *Spec) UnmarshalYAML(node *yaml.Node) error {
 				return parse.ExpectedScalarAt(valNode)
 			}
 			url := strings.TrimSpace(valNode.Value)
-			hs = &HTTPSpec{}
+			if hs == nil {
+				hs = &HTTPSpec{}
+			}
 			hs.URL = url
 		case "method":
-			if valNode.Kind != yaml.ScalarNode {
-				return parse.ExpectedScalarAt(valNode)
-			}
-			method := strings.ToUpper(strings.TrimSpace(valNode.Value))
-			if !lo.Contains(validHTTPMethods, s.Method) {
-				return InvalidHTTPMethodAt(valNode.Value, valNode)
+			method, err := parseMethod(valNode)
+			if err != nil {
+				return err
 			}
 			if hs != nil {
 				if hs.Method != "" {
@@ -165,12 +500,82 @@ func (s *Spec) UnmarshalYAML(node *yaml.Node) error {
 			hs = &HTTPSpec{}
 			hs.Method = "PATCH"
 			hs.URL = url
+		case "HEAD", "head", "http.head":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			url := strings.TrimSpace(valNode.Value)
+			if hs != nil {
+				return MultipleHTTPMethods(hs.Method, "HEAD", valNode)
+			}
+			hs = &HTTPSpec{}
+			hs.Method = "HEAD"
+			hs.URL = url
+		case "OPTIONS", "options", "http.options":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			url := strings.TrimSpace(valNode.Value)
+			if hs != nil {
+				return MultipleHTTPMethods(hs.Method, "OPTIONS", valNode)
+			}
+			hs = &HTTPSpec{}
+			hs.Method = "OPTIONS"
+			hs.URL = url
 		case "data":
 			var data interface{}
 			if err := valNode.Decode(&data); err != nil {
 				return err
 			}
 			s.Data = data
+		case "headers":
+			headers, err := parseMultiValueMap(valNode)
+			if err != nil {
+				return err
+			}
+			s.Headers = headers
+		case "query":
+			query, err := parseMultiValueMap(valNode)
+			if err != nil {
+				return err
+			}
+			s.Query = query
+		case "body":
+			var b *Body
+			if err := valNode.Decode(&b); err != nil {
+				return err
+			}
+			s.Body = b
+		case "data_file", "data-file":
+			path, err := parseFilePath(valNode)
+			if err != nil {
+				return err
+			}
+			s.DataFile = path
+		case "body_file", "body-file":
+			path, err := parseFilePath(valNode)
+			if err != nil {
+				return err
+			}
+			s.BodyFile = path
+		case "auth":
+			var auth *Auth
+			if err := valNode.Decode(&auth); err != nil {
+				return err
+			}
+			s.Auth = auth
+		case "cookies":
+			cookies, err := parseStringMap(valNode)
+			if err != nil {
+				return err
+			}
+			s.Cookies = cookies
+		case "follow_redirects":
+			var p *RedirectPolicy
+			if err := valNode.Decode(&p); err != nil {
+				return err
+			}
+			s.FollowRedirects = p
 		}
 	}
 
@@ -212,9 +617,12 @@ func (s *Spec) UnmarshalYAML(node *yaml.Node) error {
 			}
 			s.Assert = e
 		case "http.get", "http.post", "http.delete", "http.put", "http.patch",
-			"GET", "POST", "DELETE", "PUT", "PATCH",
-			"get", "post", "delete", "put", "patch",
-			"url", "method", "data":
+			"http.head", "http.options",
+			"GET", "POST", "DELETE", "PUT", "PATCH", "HEAD", "OPTIONS",
+			"get", "post", "delete", "put", "patch", "head", "options",
+			"url", "method", "data", "headers", "query", "body",
+			"data_file", "data-file", "body_file", "body-file", "auth",
+			"cookies", "follow_redirects":
 			continue
 		default:
 			if lo.Contains(api.BaseSpecFields, key) {
@@ -223,9 +631,46 @@ func (s *Spec) UnmarshalYAML(node *yaml.Node) error {
 			return parse.UnknownFieldAt(key, keyNode)
 		}
 	}
+	if hs == nil {
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			if lo.Contains(requestFields, keyNode.Value) {
+				return RequestFieldWithoutMethodAt(keyNode.Value, keyNode)
+			}
+		}
+	}
 	if s.Data != nil {
 		hs.Data = s.Data
 	}
+	if s.Headers != nil {
+		hs.Headers = s.Headers
+	}
+	if s.Query != nil {
+		hs.Query = s.Query
+	}
+	if s.Body != nil {
+		hs.Body = s.Body
+	}
+	if s.DataFile != "" {
+		hs.DataFile = s.DataFile
+	}
+	if s.BodyFile != "" {
+		hs.BodyFile = s.BodyFile
+	}
+	if s.Auth != nil {
+		hs.Auth = s.Auth
+	}
+	if s.Cookies != nil {
+		hs.Cookies = s.Cookies
+	}
+	if s.FollowRedirects != nil {
+		hs.FollowRedirects = s.FollowRedirects
+	}
+	if hs != nil {
+		if fields := hs.payloadFields(); len(fields) > 1 {
+			return MultipleRequestBodies(fields[0], fields[1], node)
+		}
+	}
 	s.HTTP = hs
 	if len(vars) > 0 {
 		s.Var = vars
@@ -246,9 +691,11 @@ func (s *HTTPSpec) UnmarshalYAML(node *yaml.Node) error {
 		}
 		key := keyNode.Value
 		switch key {
-		case "get", "put", "post", "patch", "delete",
-			"GET", "PUT", "POST", "PATCH", "DELETE",
-			"url", "method", "data":
+		case "get", "put", "post", "patch", "delete", "head", "options",
+			"GET", "PUT", "POST", "PATCH", "DELETE", "HEAD", "OPTIONS",
+			"url", "method", "data", "headers", "query", "body",
+			"data_file", "data-file", "body_file", "body-file", "auth",
+			"cookies", "follow_redirects":
 			// Because Action is an embedded struct and we parse it below, just
 			// ignore these fields in the top-level `http:` field for now.
 		default:
@@ -284,12 +731,9 @@ func (a *Action) UnmarshalYAML(node *yaml.Node) error {
 			url := strings.TrimSpace(valNode.Value)
 			a.URL = url
 		case "method":
-			if valNode.Kind != yaml.ScalarNode {
-				return parse.ExpectedScalarAt(valNode)
-			}
-			method := strings.ToUpper(strings.TrimSpace(valNode.Value))
-			if !lo.Contains(validHTTPMethods, a.Method) {
-				return InvalidHTTPMethodAt(valNode.Value, valNode)
+			method, err := parseMethod(valNode)
+			if err != nil {
+				return err
 			}
 			if a.Method != "" {
 				return MultipleHTTPMethods(a.Method, method, valNode)
@@ -345,23 +789,95 @@ func (a *Action) UnmarshalYAML(node *yaml.Node) error {
 			}
 			a.Method = "PATCH"
 			a.URL = url
+		case "HEAD", "head", "http.head":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			url := strings.TrimSpace(valNode.Value)
+			if a.Method != "" {
+				return MultipleHTTPMethods(a.Method, "HEAD", valNode)
+			}
+			a.Method = "HEAD"
+			a.URL = url
+		case "OPTIONS", "options", "http.options":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			url := strings.TrimSpace(valNode.Value)
+			if a.Method != "" {
+				return MultipleHTTPMethods(a.Method, "OPTIONS", valNode)
+			}
+			a.Method = "OPTIONS"
+			a.URL = url
 		case "data":
 			var data interface{}
 			if err := valNode.Decode(&data); err != nil {
 				return err
 			}
 			a.Data = data
+		case "headers":
+			headers, err := parseMultiValueMap(valNode)
+			if err != nil {
+				return err
+			}
+			a.Headers = headers
+		case "query":
+			query, err := parseMultiValueMap(valNode)
+			if err != nil {
+				return err
+			}
+			a.Query = query
+		case "body":
+			var b *Body
+			if err := valNode.Decode(&b); err != nil {
+				return err
+			}
+			a.Body = b
+		case "data_file", "data-file":
+			path, err := parseFilePath(valNode)
+			if err != nil {
+				return err
+			}
+			a.DataFile = path
+		case "body_file", "body-file":
+			path, err := parseFilePath(valNode)
+			if err != nil {
+				return err
+			}
+			a.BodyFile = path
+		case "auth":
+			var auth *Auth
+			if err := valNode.Decode(&auth); err != nil {
+				return err
+			}
+			a.Auth = auth
+		case "cookies":
+			cookies, err := parseStringMap(valNode)
+			if err != nil {
+				return err
+			}
+			a.Cookies = cookies
+		case "follow_redirects":
+			var p *RedirectPolicy
+			if err := valNode.Decode(&p); err != nil {
+				return err
+			}
+			a.FollowRedirects = p
 		}
 	}
+	if fields := a.payloadFields(); len(fields) > 1 {
+		return MultipleRequestBodies(fields[0], fields[1], node)
+	}
 	return nil
 }
 
-// UnmarshalYAML is a custom unmarshaler that ensures that JSONPath expressions
-// contained in the VarEntry are valid.
-func (e *VarEntry) UnmarshalYAML(node *yaml.Node) error {
+// UnmarshalYAML is a custom unmarshaler that ensures exactly one body mode is
+// specified.
+func (b *Body) UnmarshalYAML(node *yaml.Node) error {
 	if node.Kind != yaml.MappingNode {
 		return parse.ExpectedMapAt(node)
 	}
+	modes := 0
 	// maps/structs are stored in a top-level Node.Content field which is a
 	// concatenated slice of Node pointers in pairs of key/values.
 	for i := 0; i < len(node.Content); i += 2 {
@@ -372,21 +888,1287 @@ func (e *VarEntry) UnmarshalYAML(node *yaml.Node) error {
 		key := keyNode.Value
 		valNode := node.Content[i+1]
 		switch key {
-		case "from":
+		case BodyModeJSON:
+			var data interface{}
+			if err := valNode.Decode(&data); err != nil {
+				return err
+			}
+			b.JSON = data
+		case BodyModeRaw:
 			if valNode.Kind != yaml.ScalarNode {
 				return parse.ExpectedScalarAt(valNode)
 			}
-			var path string
-			if err := valNode.Decode(&path); err != nil {
+			b.Raw = valNode.Value
+		case BodyModeForm:
+			form, err := parseMultiValueMap(valNode)
+			if err != nil {
 				return err
 			}
-			if len(path) == 0 || path[0] != '$' {
-				return gdtjson.JSONPathInvalidNoRoot(path, valNode)
+			b.Form = form
+		case BodyModeMultipart:
+			if valNode.Kind != yaml.SequenceNode {
+				return parse.ExpectedSequenceAt(valNode)
 			}
-			if _, err := jsonpath.Parse(path); err != nil {
-				return gdtjson.JSONPathInvalid(path, err, valNode)
+			var parts []*Part
+			if err := valNode.Decode(&parts); err != nil {
+				return err
 			}
-			e.From = path
+			b.Multipart = parts
+		case "content_type", "content-type":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			b.ContentType = strings.TrimSpace(valNode.Value)
+			continue
+		default:
+			return parse.UnknownFieldAt(key, keyNode)
+		}
+		b.Mode = key
+		modes++
+	}
+	if modes != 1 {
+		return InvalidBodyModeAt(node)
+	}
+	return nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that ensures a multipart part has a
+// name and that any file referenced by the part exists.
+func (p *Part) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return parse.ExpectedMapAt(node)
+	}
+	hasValue := false
+	// maps/structs are stored in a top-level Node.Content field which is a
+	// concatenated slice of Node pointers in pairs of key/values.
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(keyNode)
+		}
+		key := keyNode.Value
+		valNode := node.Content[i+1]
+		if valNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(valNode)
+		}
+		switch key {
+		case "name":
+			p.Name = valNode.Value
+		case "value":
+			p.Value = valNode.Value
+			hasValue = true
+		case "file":
+			path, err := parseFilePath(valNode)
+			if err != nil {
+				return err
+			}
+			p.File = path
+		case "filename":
+			p.Filename = valNode.Value
+		case "content_type", "content-type":
+			p.ContentType = strings.TrimSpace(valNode.Value)
+		default:
+			return parse.UnknownFieldAt(key, keyNode)
+		}
+	}
+	if p.Name == "" {
+		return InvalidMultipartPartAt("missing name", node)
+	}
+	if hasValue && p.File != "" {
+		return InvalidMultipartPartAt(
+			"specify either value or file, not both", node,
+		)
+	}
+	return nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that ensures exactly one type of
+// authentication is specified.
+func (a *Auth) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return parse.ExpectedMapAt(node)
+	}
+	// maps/structs are stored in a top-level Node.Content field which is a
+	// concatenated slice of Node pointers in pairs of key/values.
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(keyNode)
+		}
+		key := keyNode.Value
+		valNode := node.Content[i+1]
+		switch key {
+		case AuthTypeBasic:
+			if valNode.Kind != yaml.MappingNode {
+				return parse.ExpectedMapAt(valNode)
+			}
+			var basic BasicAuth
+			if err := valNode.Decode(&basic); err != nil {
+				return err
+			}
+			if basic.Username == "" {
+				return InvalidAuthAt("basic requires a username", valNode)
+			}
+			a.Basic = &basic
+		case AuthTypeBearer:
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			a.Bearer = strings.TrimSpace(valNode.Value)
+		case AuthTypeAPIKey, "api-key":
+			if valNode.Kind != yaml.MappingNode {
+				return parse.ExpectedMapAt(valNode)
+			}
+			var apiKey APIKeyAuth
+			if err := valNode.Decode(&apiKey); err != nil {
+				return err
+			}
+			if (apiKey.Header == "") == (apiKey.Query == "") {
+				return InvalidAuthAt(
+					"api_key requires exactly one of header or query",
+					valNode,
+				)
+			}
+			a.APIKey = &apiKey
+			key = AuthTypeAPIKey
+		case AuthTypeOAuth2:
+			if valNode.Kind != yaml.MappingNode {
+				return parse.ExpectedMapAt(valNode)
+			}
+			var oauth2 OAuth2Auth
+			if err := valNode.Decode(&oauth2); err != nil {
+				return err
+			}
+			if oauth2.TokenURL == "" || oauth2.ClientID == "" {
+				return InvalidAuthAt(
+					"oauth2 requires a token_url and client_id", valNode,
+				)
+			}
+			a.OAuth2 = &oauth2
+		default:
+			return parse.UnknownFieldAt(key, keyNode)
+		}
+		if a.Type != "" {
+			return InvalidAuthAt(
+				"specify exactly one of: "+strings.Join(validAuthTypes, ","),
+				node,
+			)
+		}
+		a.Type = key
+	}
+	if a.Type == "" {
+		return InvalidAuthAt(
+			"specify exactly one of: "+strings.Join(validAuthTypes, ","),
+			node,
+		)
+	}
+	return nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that ensures that the format of the
+// response content, if specified, is valid.
+func (e *Expect) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return parse.ExpectedMapAt(node)
+	}
+	type expect Expect
+	var ex expect
+	if err := node.Decode(&ex); err != nil {
+		return err
+	}
+	// maps/structs are stored in a top-level Node.Content field which is a
+	// concatenated slice of Node pointers in pairs of key/values.
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		valNode := node.Content[i+1]
+		if keyNode.Value != "format" {
+			continue
+		}
+		if valNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(valNode)
+		}
+		ex.Format = strings.ToLower(strings.TrimSpace(valNode.Value))
+		if !lo.Contains(validBodyFormats, ex.Format) {
+			return InvalidBodyFormatAt(valNode.Value, valNode)
+		}
+	}
+	*e = Expect(ex)
+	return nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that accepts an HTTP status code, a
+// status class, a range of status codes, a list of any of these or a map with
+// a `not` field containing any of these.
+func (s *Status) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		in, err := parseStatusRanges(node)
+		if err != nil {
+			return err
+		}
+		s.In = in
+		return nil
+	}
+	// maps/structs are stored in a top-level Node.Content field which is a
+	// concatenated slice of Node pointers in pairs of key/values.
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(keyNode)
+		}
+		key := keyNode.Value
+		valNode := node.Content[i+1]
+		switch key {
+		case "not":
+			not, err := parseStatusRanges(valNode)
+			if err != nil {
+				return err
+			}
+			s.Not = not
+		default:
+			return parse.UnknownFieldAt(key, keyNode)
+		}
+	}
+	return nil
+}
+
+// parseStatusRanges parses a scalar or sequence YAML node containing HTTP
+// status codes, classes or ranges.
+func parseStatusRanges(node *yaml.Node) ([]StatusRange, error) {
+	nodes := []*yaml.Node{node}
+	switch node.Kind {
+	case yaml.ScalarNode:
+	case yaml.SequenceNode:
+		nodes = node.Content
+	default:
+		return nil, parse.ExpectedScalarOrSequenceAt(node)
+	}
+	ranges := make([]StatusRange, 0, len(nodes))
+	for _, n := range nodes {
+		if n.Kind != yaml.ScalarNode {
+			return nil, parse.ExpectedScalarAt(n)
+		}
+		r, ok := parseStatusRange(n.Value)
+		if !ok {
+			return nil, InvalidStatusAt(n.Value, n)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that accepts either a string of the
+// form `Key` or `Key: Value` or an object describing the HTTP header
+// assertion.
+func (h *HeaderExpect) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		key, val, _ := strings.Cut(node.Value, ":")
+		h.Name = strings.TrimSpace(key)
+		h.Value = strings.TrimSpace(val)
+		if h.Name == "" {
+			return InvalidHeaderExpectAt("missing name", node)
+		}
+		return nil
+	case yaml.MappingNode:
+	default:
+		return parse.ExpectedScalarOrMapAt(node)
+	}
+	// Decode into an alias type to avoid recursing into this unmarshaler.
+	type headerExpect HeaderExpect
+	var he headerExpect
+	// maps/structs are stored in a top-level Node.Content field which is a
+	// concatenated slice of Node pointers in pairs of key/values.
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(keyNode)
+		}
+		key := keyNode.Value
+		valNode := node.Content[i+1]
+		switch key {
+		case "name", "value", "matches", "absent", "all",
+			"lt", "lte", "gt", "gte":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+		default:
+			return parse.UnknownFieldAt(key, keyNode)
+		}
+	}
+	if err := node.Decode(&he); err != nil {
+		return err
+	}
+	*h = HeaderExpect(he)
+	h.Name = strings.TrimSpace(h.Name)
+	if h.Name == "" {
+		return InvalidHeaderExpectAt("missing name", node)
+	}
+	if h.Matches != "" {
+		re, err := regexp.Compile(h.Matches)
+		if err != nil {
+			return InvalidRegexAt(h.Matches, err, node)
+		}
+		h.re = re
+	}
+	if h.Absent && (h.Value != "" || h.Matches != "" || h.All ||
+		h.LT != nil || h.LTE != nil || h.GT != nil || h.GTE != nil) {
+		return InvalidHeaderExpectAt(
+			"absent may not be combined with other conditions", node,
+		)
+	}
+	return nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that ensures that the files
+// referenced by the TLSConfig exist and contain valid certificates and keys.
+func (c *TLSConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return parse.ExpectedMapAt(node)
+	}
+	// maps/structs are stored in a top-level Node.Content field which is a
+	// concatenated slice of Node pointers in pairs of key/values.
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(keyNode)
+		}
+		key := keyNode.Value
+		valNode := node.Content[i+1]
+		if valNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(valNode)
+		}
+		switch key {
+		case "ca_file", "cert_file", "key_file":
+			path, err := parseFilePath(valNode)
+			if err != nil {
+				return err
+			}
+			switch key {
+			case "ca_file":
+				c.CAFile = path
+			case "cert_file":
+				c.CertFile = path
+			case "key_file":
+				c.KeyFile = path
+			}
+		case "server_name":
+			c.ServerName = strings.TrimSpace(valNode.Value)
+		case "min_version":
+			c.MinVersion = strings.TrimSpace(valNode.Value)
+		case "insecure_skip_verify":
+			if err := valNode.Decode(&c.InsecureSkipVerify); err != nil {
+				return parse.ExpectedBoolAt(valNode)
+			}
+		default:
+			return parse.UnknownFieldAt(key, keyNode)
+		}
+	}
+	if err := c.load(); err != nil {
+		return InvalidTLSAt(err.Error(), node)
+	}
+	return nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that ensures that the TLS versions,
+// cipher suites and certificate expiry condition in the TLSExpect are valid.
+func (e *TLSExpect) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return parse.ExpectedMapAt(node)
+	}
+	// maps/structs are stored in a top-level Node.Content field which is a
+	// concatenated slice of Node pointers in pairs of key/values.
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(keyNode)
+		}
+		key := keyNode.Value
+		valNode := node.Content[i+1]
+		switch key {
+		case "version":
+			versions, err := parseScalarOrSequence(valNode)
+			if err != nil {
+				return err
+			}
+			for _, v := range versions {
+				if _, ok := tlsVersions[v]; !ok {
+					return InvalidTLSAt(
+						fmt.Sprintf(
+							"invalid version %q. valid versions: "+
+								"1.0, 1.1, 1.2, 1.3",
+							v,
+						),
+						valNode,
+					)
+				}
+			}
+			e.Version = versions
+		case "cipher_suite":
+			suites, err := parseScalarOrSequence(valNode)
+			if err != nil {
+				return err
+			}
+			for _, suite := range suites {
+				if !validCipherSuite(suite) {
+					return InvalidTLSAt(
+						fmt.Sprintf("unknown cipher suite %q", suite),
+						valNode,
+					)
+				}
+			}
+			e.CipherSuite = suites
+		case "sans":
+			sans, err := parseScalarOrSequence(valNode)
+			if err != nil {
+				return err
+			}
+			e.SANs = sans
+		case "alpn", "subject", "issuer", "expires_in_days":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			val := strings.TrimSpace(valNode.Value)
+			switch key {
+			case "alpn":
+				e.ALPN = &val
+			case "subject":
+				e.Subject = val
+			case "issuer":
+				e.Issuer = val
+			case "expires_in_days":
+				cond, err := parseDaysCondition(val)
+				if err != nil {
+					return InvalidTLSAt(
+						fmt.Sprintf("invalid expires_in_days: %s", err),
+						valNode,
+					)
+				}
+				e.ExpiresInDays = val
+				e.expiry = cond
+			}
+		default:
+			return parse.UnknownFieldAt(key, keyNode)
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that accepts either a bool, where true
+// follows up to DefaultMaxRedirects redirects, or the maximum number of
+// redirects to follow.
+func (p *RedirectPolicy) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return parse.ExpectedScalarAt(node)
+	}
+	var follow bool
+	if err := node.Decode(&follow); err == nil {
+		p.Max = 0
+		if follow {
+			p.Max = DefaultMaxRedirects
+		}
+		return nil
+	}
+	var max int
+	if err := node.Decode(&max); err != nil || max < 0 {
+		return InvalidFollowRedirectsAt(node)
+	}
+	p.Max = max
+	return nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that accepts either a string
+// containing the expected Location of the redirect or an object describing
+// the redirect assertion.
+func (e *RedirectExpect) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		e.Location = strings.TrimSpace(node.Value)
+		return nil
+	case yaml.MappingNode:
+	default:
+		return parse.ExpectedScalarOrMapAt(node)
+	}
+	// maps/structs are stored in a top-level Node.Content field which is a
+	// concatenated slice of Node pointers in pairs of key/values.
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(keyNode)
+		}
+		key := keyNode.Value
+		valNode := node.Content[i+1]
+		switch key {
+		case "status":
+			var status *Status
+			if err := valNode.Decode(&status); err != nil {
+				return err
+			}
+			e.Status = status
+		case "location":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			e.Location = strings.TrimSpace(valNode.Value)
+		case "matches":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			re, err := regexp.Compile(valNode.Value)
+			if err != nil {
+				return InvalidRegexAt(valNode.Value, err, valNode)
+			}
+			e.Matches = valNode.Value
+			e.re = re
+		default:
+			return parse.UnknownFieldAt(key, keyNode)
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that accepts either a string
+// containing the name of a cookie or an object describing the cookie
+// assertion.
+func (e *CookieExpect) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		e.Name = strings.TrimSpace(node.Value)
+		if e.Name == "" {
+			return InvalidCookieExpectAt("missing name", node)
+		}
+		return nil
+	case yaml.MappingNode:
+	default:
+		return parse.ExpectedScalarOrMapAt(node)
+	}
+	// maps/structs are stored in a top-level Node.Content field which is a
+	// concatenated slice of Node pointers in pairs of key/values.
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(keyNode)
+		}
+		key := keyNode.Value
+		valNode := node.Content[i+1]
+		if valNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(valNode)
+		}
+		val := valNode.Value
+		switch key {
+		case "name":
+			e.Name = strings.TrimSpace(val)
+		case "value":
+			e.Value = &val
+		case "matches":
+			re, err := regexp.Compile(val)
+			if err != nil {
+				return InvalidRegexAt(val, err, valNode)
+			}
+			e.Matches = val
+			e.re = re
+		case "path":
+			e.Path = &val
+		case "domain":
+			e.Domain = &val
+		case "same_site":
+			sameSite := strings.ToLower(strings.TrimSpace(val))
+			if !slices.Contains(validSameSites, sameSite) {
+				return InvalidCookieExpectAt(
+					fmt.Sprintf(
+						"invalid same_site %q. valid values: %s",
+						val, strings.Join(validSameSites, ", "),
+					),
+					valNode,
+				)
+			}
+			e.SameSite = sameSite
+		case "expires":
+			cond, err := parseDurationCondition(val)
+			if err != nil {
+				return InvalidCookieExpectAt(
+					fmt.Sprintf("invalid expires: %s", err), valNode,
+				)
+			}
+			e.Expires = cond
+		case "absent", "secure", "http_only", "session":
+			var b bool
+			if err := valNode.Decode(&b); err != nil {
+				return parse.ExpectedBoolAt(valNode)
+			}
+			switch key {
+			case "absent":
+				e.Absent = b
+			case "secure":
+				e.Secure = &b
+			case "http_only":
+				e.HTTPOnly = &b
+			case "session":
+				e.Session = &b
+			}
+		default:
+			return parse.UnknownFieldAt(key, keyNode)
+		}
+	}
+	if e.Name == "" {
+		return InvalidCookieExpectAt("missing name", node)
+	}
+	if e.Absent && (e.Value != nil || e.Matches != "" || e.Secure != nil ||
+		e.HTTPOnly != nil || e.SameSite != "" || e.Path != nil ||
+		e.Domain != nil || e.Session != nil || e.Expires != nil) {
+		return InvalidCookieExpectAt(
+			"absent may not be combined with other conditions", node,
+		)
+	}
+	return nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that ensures regular expressions
+// contained in the BodyExpect are valid and that any file referenced by the
+// BodyExpect exists.
+func (e *BodyExpect) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return parse.ExpectedMapAt(node)
+	}
+	// maps/structs are stored in a top-level Node.Content field which is a
+	// concatenated slice of Node pointers in pairs of key/values.
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(keyNode)
+		}
+		key := keyNode.Value
+		valNode := node.Content[i+1]
+		switch key {
+		case "contains":
+			vals, err := parseScalarOrSequence(valNode)
+			if err != nil {
+				return err
+			}
+			e.Contains = vals
+		case "not_contains", "not-contains":
+			vals, err := parseScalarOrSequence(valNode)
+			if err != nil {
+				return err
+			}
+			e.NotContains = vals
+		case "matches":
+			vals, err := parseScalarOrSequence(valNode)
+			if err != nil {
+				return err
+			}
+			for _, expr := range vals {
+				re, err := regexp.Compile(expr)
+				if err != nil {
+					return InvalidRegexAt(expr, err, valNode)
+				}
+				e.matches = append(e.matches, re)
+			}
+			e.Matches = vals
+		case "equals":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			equals := valNode.Value
+			e.Equals = &equals
+		case "equals_file", "equals-file":
+			path, err := parseFilePath(valNode)
+			if err != nil {
+				return err
+			}
+			e.EqualsFile = path
+		default:
+			return parse.UnknownFieldAt(key, keyNode)
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that accepts either a string
+// containing a CSS selector or an object describing the assertion, and
+// ensures that the CSS selector is valid.
+func (e *HTMLExpect) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		sel, err := cascadia.Compile(node.Value)
+		if err != nil {
+			return InvalidSelectorAt(node.Value, err, node)
+		}
+		e.Selector = node.Value
+		e.sel = sel
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return parse.ExpectedScalarOrMapAt(node)
+	}
+	var selectorNode *yaml.Node
+	// maps/structs are stored in a top-level Node.Content field which is a
+	// concatenated slice of Node pointers in pairs of key/values.
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(keyNode)
+		}
+		key := keyNode.Value
+		valNode := node.Content[i+1]
+		switch key {
+		case "selector":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			sel, err := cascadia.Compile(valNode.Value)
+			if err != nil {
+				return InvalidSelectorAt(valNode.Value, err, valNode)
+			}
+			e.Selector = valNode.Value
+			e.sel = sel
+			selectorNode = valNode
+		case "count":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			var count int
+			if err := valNode.Decode(&count); err != nil {
+				return parse.ExpectedIntAt(valNode)
+			}
+			e.Count = &count
+		case "text":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			text := valNode.Value
+			e.Text = &text
+		case "contains":
+			vals, err := parseScalarOrSequence(valNode)
+			if err != nil {
+				return err
+			}
+			e.Contains = vals
+		case "attrs":
+			attrs, err := parseStringMap(valNode)
+			if err != nil {
+				return err
+			}
+			e.Attrs = attrs
+		default:
+			return parse.UnknownFieldAt(key, keyNode)
+		}
+	}
+	if selectorNode == nil {
+		return InvalidHTMLExpectAt("missing selector", node)
+	}
+	return nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that ensures that XPath expressions
+// contained in the XMLExpect are valid and that any XSD document exists.
+func (e *XMLExpect) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return parse.ExpectedMapAt(node)
+	}
+	// maps/structs are stored in a top-level Node.Content field which is a
+	// concatenated slice of Node pointers in pairs of key/values.
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(keyNode)
+		}
+		key := keyNode.Value
+		valNode := node.Content[i+1]
+		switch key {
+		case "paths":
+			if valNode.Kind != yaml.MappingNode {
+				return parse.ExpectedMapAt(valNode)
+			}
+			paths := map[string]string{}
+			if err := valNode.Decode(&paths); err != nil {
+				return err
+			}
+			for expr := range paths {
+				if err := e.compile(expr); err != nil {
+					return InvalidXPathAt(expr, err, valNode)
+				}
+			}
+			e.Paths = paths
+		case "exists":
+			exprs, err := parseScalarOrSequence(valNode)
+			if err != nil {
+				return err
+			}
+			for _, expr := range exprs {
+				if err := e.compile(expr); err != nil {
+					return InvalidXPathAt(expr, err, valNode)
+				}
+			}
+			e.Exists = exprs
+		case "count":
+			if valNode.Kind != yaml.MappingNode {
+				return parse.ExpectedMapAt(valNode)
+			}
+			counts := map[string]int{}
+			if err := valNode.Decode(&counts); err != nil {
+				return err
+			}
+			for expr := range counts {
+				if err := e.compile(expr); err != nil {
+					return InvalidXPathAt(expr, err, valNode)
+				}
+			}
+			e.Count = counts
+		case "schema":
+			path, err := parseFilePath(valNode)
+			if err != nil {
+				return err
+			}
+			if !xsdSupported {
+				return XSDNotSupportedAt(valNode)
+			}
+			e.Schema = path
+		default:
+			return parse.UnknownFieldAt(key, keyNode)
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that ensures that exactly one location
+// of the JWT is specified, that any JSONPath expression is valid and that any
+// key file can be read.
+func (e *JWTExpect) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return parse.ExpectedMapAt(node)
+	}
+	sources := 0
+	// maps/structs are stored in a top-level Node.Content field which is a
+	// concatenated slice of Node pointers in pairs of key/values.
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(keyNode)
+		}
+		key := keyNode.Value
+		valNode := node.Content[i+1]
+		switch key {
+		case "from", "header", "cookie", "jwks_url":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			val := strings.TrimSpace(valNode.Value)
+			if val == "" {
+				return InvalidJWTExpectAt(
+					fmt.Sprintf("%s must not be empty", key), valNode,
+				)
+			}
+			switch key {
+			case "from":
+				if val[0] != '$' {
+					return gdtjson.JSONPathInvalidNoRoot(val, valNode)
+				}
+				if _, err := jsonpath.Parse(val); err != nil {
+					return gdtjson.JSONPathInvalid(val, err, valNode)
+				}
+				e.From = val
+				sources++
+			case "header":
+				e.Header = val
+				sources++
+			case "cookie":
+				e.Cookie = val
+				sources++
+			case "jwks_url":
+				if e.KeyFile != "" {
+					return InvalidJWTExpectAt(
+						"only one of key_file or jwks_url may be specified",
+						valNode,
+					)
+				}
+				e.JWKSURL = val
+			}
+		case "key_file":
+			if e.JWKSURL != "" {
+				return InvalidJWTExpectAt(
+					"only one of key_file or jwks_url may be specified",
+					valNode,
+				)
+			}
+			path, err := parseFilePath(valNode)
+			if err != nil {
+				return err
+			}
+			key, err := readJWTKey(path)
+			if err != nil {
+				return InvalidJWTExpectAt(
+					fmt.Sprintf("cannot read key_file %s: %s", path, err),
+					valNode,
+				)
+			}
+			e.KeyFile = path
+			e.key = key
+		case "token_header":
+			vals, err := parseStringMap(valNode)
+			if err != nil {
+				return err
+			}
+			e.TokenHeader = vals
+		case "claims":
+			vals, err := parseStringMap(valNode)
+			if err != nil {
+				return err
+			}
+			e.Claims = vals
+		case "not_expired":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			if err := valNode.Decode(&e.NotExpired); err != nil {
+				return parse.ExpectedBoolAt(valNode)
+			}
+		case "scopes":
+			vals, err := parseScalarOrSequence(valNode)
+			if err != nil {
+				return err
+			}
+			e.Scopes = vals
+		default:
+			return parse.UnknownFieldAt(key, keyNode)
+		}
+	}
+	if sources != 1 {
+		return InvalidJWTExpectAt(
+			"exactly one of from, header or cookie must be specified", node,
+		)
+	}
+	return nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that accepts a map, keyed by the name
+// of a phase of the HTTP request, of duration conditions such as `<200ms`.
+func (e *LatencyExpect) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return parse.ExpectedMapAt(node)
+	}
+	// maps/structs are stored in a top-level Node.Content field which is a
+	// concatenated slice of Node pointers in pairs of key/values.
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(keyNode)
+		}
+		key := keyNode.Value
+		valNode := node.Content[i+1]
+		if valNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(valNode)
+		}
+		cond, err := parseDurationCondition(valNode.Value)
+		if err != nil {
+			return InvalidLatencyAt(err.Error(), valNode)
+		}
+		switch key {
+		case LatencyDNS:
+			e.DNS = cond
+		case LatencyConnect:
+			e.Connect = cond
+		case LatencyTLS:
+			e.TLS = cond
+		case LatencyTTFB:
+			e.TTFB = cond
+		case LatencyTotal:
+			e.Total = cond
+		default:
+			return parse.UnknownFieldAt(key, keyNode)
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that accepts either a string with the
+// path to the snapshot file or an object describing the snapshot assertion,
+// and ensures the JSONPath expressions in the ignore list are valid.
+func (e *SnapshotExpect) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		path, err := filepath.Abs(strings.TrimSpace(node.Value))
+		if err != nil {
+			return err
+		}
+		e.File = path
+		return nil
+	case yaml.MappingNode:
+	default:
+		return parse.ExpectedScalarOrMapAt(node)
+	}
+	// maps/structs are stored in a top-level Node.Content field which is a
+	// concatenated slice of Node pointers in pairs of key/values.
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(keyNode)
+		}
+		key := keyNode.Value
+		valNode := node.Content[i+1]
+		switch key {
+		case "file":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			// The snapshot file may not exist until it is recorded, so
+			// unlike parseFilePath we don't check the file exists.
+			path, err := filepath.Abs(strings.TrimSpace(valNode.Value))
+			if err != nil {
+				return err
+			}
+			e.File = path
+		case "ignore":
+			paths, err := parseScalarOrSequence(valNode)
+			if err != nil {
+				return err
+			}
+			for _, path := range paths {
+				p, err := jsonpath.Parse(path)
+				if err != nil {
+					return gdtjson.JSONPathInvalid(path, err, valNode)
+				}
+				e.ignore = append(e.ignore, p)
+			}
+			e.Ignore = paths
+		default:
+			return parse.UnknownFieldAt(key, keyNode)
+		}
+	}
+	if e.File == "" {
+		return InvalidSnapshotAt("missing file", node)
+	}
+	return nil
+}
+
+// parseScalarOrSequence parses a YAML node that is either a single scalar or a
+// sequence of scalars.
+func parseScalarOrSequence(node *yaml.Node) ([]string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return []string{node.Value}, nil
+	case yaml.SequenceNode:
+		vals := make([]string, 0, len(node.Content))
+		for _, itemNode := range node.Content {
+			if itemNode.Kind != yaml.ScalarNode {
+				return nil, parse.ExpectedScalarAt(itemNode)
+			}
+			vals = append(vals, itemNode.Value)
+		}
+		return vals, nil
+	}
+	return nil, parse.ExpectedScalarOrSequenceAt(node)
+}
+
+// parseFilePath returns the absolute path to the file referenced by the
+// supplied scalar YAML node, returning a parse error if the file does not
+// exist.
+func parseFilePath(node *yaml.Node) (string, error) {
+	if node.Kind != yaml.ScalarNode {
+		return "", parse.ExpectedScalarAt(node)
+	}
+	// Relative filepaths are resolved relative to the test scenario's
+	// directory, which is the current working directory during parse.
+	path, _ := filepath.Abs(strings.TrimPrefix(node.Value, "file://"))
+	if _, err := os.Stat(path); err != nil {
+		return "", parse.FileNotFoundAt(path, node)
+	}
+	return path, nil
+}
+
+// parseMultiValueMap parses a YAML mapping node whose values are either a
+// single scalar or a sequence of scalars, such as a map of HTTP headers.
+func parseMultiValueMap(node *yaml.Node) (map[string][]string, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, parse.ExpectedMapAt(node)
+	}
+	res := make(map[string][]string, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return nil, parse.ExpectedScalarAt(keyNode)
+		}
+		key := keyNode.Value
+		valNode := node.Content[i+1]
+		switch valNode.Kind {
+		case yaml.ScalarNode:
+			res[key] = append(res[key], valNode.Value)
+		case yaml.SequenceNode:
+			for _, itemNode := range valNode.Content {
+				if itemNode.Kind != yaml.ScalarNode {
+					return nil, parse.ExpectedScalarAt(itemNode)
+				}
+				res[key] = append(res[key], itemNode.Value)
+			}
+		default:
+			return nil, parse.ExpectedScalarOrSequenceAt(valNode)
+		}
+	}
+	return res, nil
+}
+
+// parseStringMap parses a YAML mapping node whose values are scalars, such as
+// a map of cookies.
+func parseStringMap(node *yaml.Node) (map[string]string, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, parse.ExpectedMapAt(node)
+	}
+	res := make(map[string]string, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return nil, parse.ExpectedScalarAt(keyNode)
+		}
+		valNode := node.Content[i+1]
+		if valNode.Kind != yaml.ScalarNode {
+			return nil, parse.ExpectedScalarAt(valNode)
+		}
+		res[keyNode.Value] = valNode.Value
+	}
+	return res, nil
+}
+
+// UnmarshalYAML is a custom unmarshaler that ensures that JSONPath expressions
+// and regular expressions contained in the VarEntry are valid and that exactly
+// one source for the variable's value is specified.
+func (e *VarEntry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return parse.ExpectedMapAt(node)
+	}
+	sources := 0
+	var groupNode *yaml.Node
+	var allNode *yaml.Node
+	var attrNode *yaml.Node
+	// maps/structs are stored in a top-level Node.Content field which is a
+	// concatenated slice of Node pointers in pairs of key/values.
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if keyNode.Kind != yaml.ScalarNode {
+			return parse.ExpectedScalarAt(keyNode)
+		}
+		key := keyNode.Value
+		valNode := node.Content[i+1]
+		switch key {
+		case "from":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			var path string
+			if err := valNode.Decode(&path); err != nil {
+				return err
+			}
+			if len(path) == 0 || path[0] != '$' {
+				return gdtjson.JSONPathInvalidNoRoot(path, valNode)
+			}
+			if _, err := jsonpath.Parse(path); err != nil {
+				return gdtjson.JSONPathInvalid(path, err, valNode)
+			}
+			e.From = path
+			sources++
+		case "all":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			var all bool
+			if err := valNode.Decode(&all); err != nil {
+				return parse.ExpectedBoolAt(valNode)
+			}
+			e.All = all
+			allNode = valNode
+		case "header":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			e.Header = strings.TrimSpace(valNode.Value)
+			sources++
+		case "status":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			var status bool
+			if err := valNode.Decode(&status); err != nil {
+				return parse.ExpectedBoolAt(valNode)
+			}
+			e.Status = status
+			if status {
+				sources++
+			}
+		case "latency":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			phase := strings.ToLower(strings.TrimSpace(valNode.Value))
+			if !lo.Contains(validLatencyPhases, phase) {
+				return InvalidLatencyAt(
+					fmt.Sprintf(
+						"unknown phase %q. valid phases: %s",
+						valNode.Value, strings.Join(validLatencyPhases, ", "),
+					),
+					valNode,
+				)
+			}
+			e.Latency = phase
+			sources++
+		case "cookie":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			e.Cookie = strings.TrimSpace(valNode.Value)
+			sources++
+		case "regex":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			if _, err := regexp.Compile(valNode.Value); err != nil {
+				return InvalidRegexAt(valNode.Value, err, valNode)
+			}
+			e.Regex = valNode.Value
+			sources++
+		case "xpath":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			if _, err := xpath.Compile(valNode.Value); err != nil {
+				return InvalidXPathAt(valNode.Value, err, valNode)
+			}
+			e.XPath = valNode.Value
+			sources++
+		case "selector":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			if _, err := cascadia.Compile(valNode.Value); err != nil {
+				return InvalidSelectorAt(valNode.Value, err, valNode)
+			}
+			e.Selector = valNode.Value
+			sources++
+		case "attr":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			e.Attr = strings.TrimSpace(valNode.Value)
+			attrNode = valNode
+		case "group":
+			if valNode.Kind != yaml.ScalarNode {
+				return parse.ExpectedScalarAt(valNode)
+			}
+			e.Group = strings.TrimSpace(valNode.Value)
+			groupNode = valNode
+		}
+	}
+	if sources != 1 {
+		return InvalidVarSourceAt(node)
+	}
+	if allNode != nil && e.From == "" {
+		return InvalidVarSourceAt(allNode)
+	}
+	if attrNode != nil && e.Selector == "" {
+		return InvalidVarSourceAt(attrNode)
+	}
+	if groupNode != nil {
+		if e.Regex == "" {
+			return InvalidVarSourceAt(groupNode)
+		}
+		if regexGroupIndex(regexp.MustCompile(e.Regex), e.Group) < 0 {
+			return InvalidRegexGroupAt(e.Group, e.Regex, groupNode)
 		}
 	}
 	return nil
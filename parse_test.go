@@ -46,30 +46,406 @@ func TestParseFailures(t *testing.T) {
 	assert := assert.New(t)
 	require := require.New(t)
 
-	fp := filepath.Join("testdata", "parse", "fail", "invalid.yaml")
+	tests := []struct {
+		file   string
+		expErr string
+	}{
+		{"invalid.yaml", "expected map"},
+		{
+			"request-field-without-method.yaml",
+			"request field headers requires a method",
+		},
+	}
+	for _, test := range tests {
+		fp := filepath.Join("testdata", "parse", "fail", test.file)
+		f, err := os.Open(fp)
+		require.Nil(err)
+		defer f.Close() // nolint:errcheck
+
+		s, err := scenario.FromReader(f, scenario.WithPath(fp))
+		require.NotNil(err, test.file)
+		assert.Error(err, &parse.Error{})
+		assert.ErrorContains(err, test.expErr)
+		require.Nil(s)
+	}
+}
+
+func TestMissingSchema(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "missing-schema.yaml")
 	f, err := os.Open(fp)
 	require.Nil(err)
 	defer f.Close() // nolint:errcheck
 
 	s, err := scenario.FromReader(f, scenario.WithPath(fp))
 	require.NotNil(err)
-	assert.Error(err, &parse.Error{})
-	assert.ErrorContains(err, "expected map")
+	assert.ErrorContains(err, "unable to find JSONSchema file")
 	require.Nil(s)
 }
 
-func TestMissingSchema(t *testing.T) {
+func TestDataAndBody(t *testing.T) {
 	assert := assert.New(t)
 	require := require.New(t)
 
-	fp := filepath.Join("testdata", "parse", "fail", "missing-schema.yaml")
+	fp := filepath.Join("testdata", "parse", "fail", "data-and-body.yaml")
 	f, err := os.Open(fp)
 	require.Nil(err)
 	defer f.Close() // nolint:errcheck
 
 	s, err := scenario.FromReader(f, scenario.WithPath(fp))
 	require.NotNil(err)
-	assert.ErrorContains(err, "unable to find JSONSchema file")
+	assert.ErrorContains(err, "multiple request payloads specified")
+	require.Nil(s)
+}
+
+func TestMissingMultipartFile(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "missing-multipart-file.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "file not found")
+	require.Nil(s)
+}
+
+func TestMissingDataFile(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "missing-data-file.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "file not found")
+	assert.ErrorContains(err, "doesnotexist.json")
+	require.Nil(s)
+}
+
+func TestVarMultipleSources(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "var-multiple-sources.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "var must specify exactly one of")
+	require.Nil(s)
+}
+
+func TestAuthMultipleTypes(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "auth-multiple-types.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "invalid auth: specify exactly one of")
+	require.Nil(s)
+}
+
+func TestUnregisteredMethod(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "unregistered-method.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "invalid HTTP method specified: FROBNICATE")
+	require.Nil(s)
+}
+
+func TestRegisterInvalidMethod(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	err := gdthttp.RegisterMethod("PURGE", "NOT A METHOD")
+	assert.ErrorContains(err, `invalid HTTP method: "NOT A METHOD"`)
+
+	// none of the methods are registered when one of them is invalid
+	fp := filepath.Join(
+		"testdata", "parse", "fail", "invalid-method-registration.yaml",
+	)
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "invalid HTTP method specified: PURGE")
+	require.Nil(s)
+}
+
+func TestMethodsRetry(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	require.Nil(gdthttp.RegisterMethod("PROPFIND"))
+
+	fp := filepath.Join("testdata", "methods.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+	require.Len(s.Tests, 4)
+
+	expMethods := []string{"HEAD", "OPTIONS", "TRACE", "PROPFIND"}
+	for x, st := range s.Tests {
+		sth := st.(*gdthttp.Spec)
+		assert.Equal(expMethods[x], sth.HTTP.Method)
+	}
+	// safe methods use the plugin's default retry
+	assert.Nil(s.Tests[0].Retry())
+	assert.Nil(s.Tests[2].Retry())
+	// extension methods are not retried
+	assert.Equal(api.NoRetry, s.Tests[3].Retry())
+}
+
+func TestIdempotentMethodsRetry(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "retry.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+	require.Len(s.Tests, 4)
+
+	// PUT and DELETE are idempotent and use the plugin's default retry
+	assert.Nil(s.Tests[0].Retry())
+	assert.Nil(s.Tests[1].Retry())
+	// POST and PATCH are not idempotent and are not retried
+	assert.Equal(api.NoRetry, s.Tests[2].Retry())
+	assert.Equal(api.NoRetry, s.Tests[3].Retry())
+}
+
+func TestInvalidStatus(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "invalid-status.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "invalid HTTP status specified: 600")
+	require.Nil(s)
+}
+
+func TestInvalidLatency(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "invalid-latency.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "invalid latency")
+	require.Nil(s)
+}
+
+func TestInvalidXPath(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "invalid-xpath.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "invalid XPath expression //book[")
+	require.Nil(s)
+}
+
+func TestInvalidSelector(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "invalid-selector.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "invalid CSS selector form[name=")
+	require.Nil(s)
+}
+
+func TestInvalidBodyFormat(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "invalid-body-format.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, `invalid format "toml"`)
+	require.Nil(s)
+}
+
+func TestJWTMultipleSources(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "jwt-multiple-sources.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "exactly one of from, header or cookie")
+	require.Nil(s)
+}
+
+func TestInvalidCookieSameSite(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "invalid-cookie-same-site.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "invalid same_site \"sometimes\"")
+	require.Nil(s)
+}
+
+func TestInvalidFollowRedirects(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "invalid-follow-redirects.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "invalid follow_redirects: sometimes")
+	require.Nil(s)
+}
+
+func TestTLSCertWithoutKey(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "tls-cert-without-key.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "cert_file and key_file must be specified together")
+	require.Nil(s)
+}
+
+func TestInvalidTLSVersion(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "invalid-tls-version.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "invalid version \"1.4\"")
+	require.Nil(s)
+}
+
+func TestParseStatus(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "status.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	tests := []struct {
+		exp      string
+		matching []int
+		failing  []int
+	}{
+		{"one of 200, 204", []int{200, 204}, []int{201, 404}},
+		{"2xx", []int{200, 299}, []int{199, 300}},
+		{"400-404", []int{400, 404}, []int{399, 405}},
+		{"not 2xx, 5xx", []int{301, 404}, []int{200, 503}},
+		{"not 500", []int{200, 503}, []int{500}},
+	}
+	require.Len(s.Tests, len(tests))
+	for x, tt := range tests {
+		status := s.Tests[x].(*gdthttp.Spec).Assert.Status
+		assert.Equal(tt.exp, status.String())
+		for _, code := range tt.matching {
+			assert.True(status.Matches(code), "%s: %d", tt.exp, code)
+		}
+		for _, code := range tt.failing {
+			assert.False(status.Matches(code), "%s: %d", tt.exp, code)
+		}
+	}
+}
+
+func TestHeaderAbsentWithValue(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "parse", "fail", "header-absent-with-value.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "absent may not be combined with other conditions")
 	require.Nil(s)
 }
 
@@ -85,9 +461,6 @@ func TestParse(t *testing.T) {
 	s, err := scenario.FromReader(f, scenario.WithPath(fp))
 	require.Nil(err)
 
-	code404 := 404
-	code200 := 200
-	code201 := 201
 	len0 := 0
 	dateOnly := "2006-01-02"
 	publishedOn1940, _ := time.Parse(dateOnly, "1940-10-21")
@@ -125,7 +498,7 @@ func TestParse(t *testing.T) {
 				JSON: &gdtjson.Expect{
 					Len: &len0,
 				},
-				Status: &code404,
+				Status: gdthttp.StatusCode(404),
 			},
 		},
 		&gdthttp.Spec{
@@ -144,7 +517,7 @@ func TestParse(t *testing.T) {
 				JSON: &gdtjson.Expect{
 					Schema: schemaPath,
 				},
-				Status: &code200,
+				Status: gdthttp.StatusCode(200),
 			},
 		},
 		&gdthttp.Spec{
@@ -167,9 +540,9 @@ func TestParse(t *testing.T) {
 				},
 			},
 			Assert: &gdthttp.Expect{
-				Status: &code201,
-				Headers: []string{
-					"Location",
+				Status: gdthttp.StatusCode(201),
+				Headers: []*gdthttp.HeaderExpect{
+					{Name: "Location"},
 				},
 			},
 		},
@@ -195,7 +568,7 @@ func TestParse(t *testing.T) {
 						"$.id": "uuid4",
 					},
 				},
-				Status: &code200,
+				Status: gdthttp.StatusCode(200),
 			},
 		},
 		&gdthttp.Spec{
@@ -227,7 +600,7 @@ func TestParse(t *testing.T) {
 				},
 			},
 			Assert: &gdthttp.Expect{
-				Status: &code200,
+				Status: gdthttp.StatusCode(200),
 			},
 		},
 	}
@@ -0,0 +1,203 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+package http
+
+import (
+	"context"
+	"fmt"
+	nethttp "net/http"
+	neturl "net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxRedirects is the number of redirects followed when
+// `follow_redirects` is true. It is the same limit net/http applies by
+// default.
+const DefaultMaxRedirects = 10
+
+// RedirectPolicy describes whether, and how many, HTTP redirects are followed
+// when sending an HTTP request.
+type RedirectPolicy struct {
+	// Max is the maximum number of redirects that are followed. When the
+	// limit is reached, the redirect response is returned as the HTTP
+	// response. Zero means that redirects are not followed.
+	Max int
+}
+
+// checkRedirect is a net/http.Client CheckRedirect function that stops
+// following redirects once the policy's maximum is reached.
+func (p *RedirectPolicy) checkRedirect(
+	req *nethttp.Request,
+	via []*nethttp.Request,
+) error {
+	if len(via) > p.Max {
+		return nethttp.ErrUseLastResponse
+	}
+	return nil
+}
+
+// withRedirectPolicy returns a copy of the supplied HTTP client that follows
+// redirects according to the supplied policy. If the policy is nil, the
+// supplied HTTP client is returned unchanged.
+func withRedirectPolicy(
+	c *nethttp.Client,
+	p *RedirectPolicy,
+) *nethttp.Client {
+	if p == nil {
+		return c
+	}
+	rc := *c
+	rc.CheckRedirect = p.checkRedirect
+	return &rc
+}
+
+// redirectPolicyFor returns the redirect policy for the action's HTTP
+// request: the action's own policy if it has one, otherwise the policy in the
+// supplied defaults, if any.
+func (a *Action) redirectPolicyFor(defaults *Defaults) *RedirectPolicy {
+	if a.FollowRedirects != nil {
+		return a.FollowRedirects
+	}
+	if defaults != nil {
+		return defaults.FollowRedirects
+	}
+	return nil
+}
+
+// RedirectHop describes a redirect response that was followed on the way to
+// the final HTTP response.
+type RedirectHop struct {
+	// URL is the URL of the HTTP request that was redirected.
+	URL string
+	// Status is the HTTP status code of the redirect response.
+	Status int
+	// Location is the value of the Location HTTP header of the redirect
+	// response.
+	Location string
+}
+
+// String returns the hop in the form `302 /from -> /to`.
+func (h *RedirectHop) String() string {
+	return fmt.Sprintf("%d %s -> %s", h.Status, h.URL, h.Location)
+}
+
+// redirectChain returns the redirects that were followed, in order, to
+// arrive at the supplied HTTP response.
+func redirectChain(resp *nethttp.Response) []*RedirectHop {
+	hops := []*RedirectHop{}
+	if resp == nil || resp.Request == nil {
+		return hops
+	}
+	// Each request made to follow a redirect refers to the redirect response
+	// that caused it, so the chain is walked backwards from the final
+	// response.
+	r := resp.Request.Response
+	for r != nil && r.Request != nil {
+		hops = append(hops, &RedirectHop{
+			URL:      r.Request.URL.String(),
+			Status:   r.StatusCode,
+			Location: r.Header.Get("Location"),
+		})
+		r = r.Request.Response
+	}
+	slices.Reverse(hops)
+	return hops
+}
+
+// RedirectExpect describes an assertion about a single redirect followed on
+// the way to the final HTTP response.
+type RedirectExpect struct {
+	// Status describes the expected HTTP status code of the redirect
+	// response, in any of the forms accepted by `assert.status`.
+	Status *Status `yaml:"status,omitempty"`
+	// Location is the expected Location HTTP header of the redirect
+	// response. It may be written as it appears in the Location header, as
+	// an absolute URL or, when the redirect is to the same host, as a path
+	// with an optional query string. Variable references are interpolated.
+	Location string `yaml:"location,omitempty"`
+	// Matches is a regular expression the Location HTTP header of the
+	// redirect response must match.
+	Matches string `yaml:"matches,omitempty"`
+	// re is the compiled Matches regular expression.
+	re *regexp.Regexp
+}
+
+// check returns nil if the supplied redirect satisfies the assertion,
+// otherwise it returns the failure. The hop's number in the redirect chain,
+// starting at 1, is used in the failure.
+func (e *RedirectExpect) check(
+	ctx context.Context,
+	num int,
+	hop *RedirectHop,
+) error {
+	if e.Status != nil && !e.Status.Matches(hop.Status) {
+		return HTTPRedirectNotMatched(
+			num, fmt.Sprintf("status %v", e.Status), strconv.Itoa(hop.Status),
+		)
+	}
+	if e.Location != "" {
+		exp, err := expandString(ctx, e.Location)
+		if err != nil {
+			return err
+		}
+		if !locationMatches(hop, exp) {
+			return HTTPRedirectNotMatched(
+				num, fmt.Sprintf("Location %q", exp), hop.Location,
+			)
+		}
+	}
+	if e.re != nil && !e.re.MatchString(hop.Location) {
+		return HTTPRedirectNotMatched(
+			num, fmt.Sprintf("Location matching %q", e.Matches), hop.Location,
+		)
+	}
+	return nil
+}
+
+// locationMatches returns true if the Location of the supplied redirect is
+// the expected location, written as it appears in the Location header, as an
+// absolute URL or, for a redirect to the same host, as a path.
+func locationMatches(hop *RedirectHop, exp string) bool {
+	if hop.Location == exp {
+		return true
+	}
+	from, err := neturl.Parse(hop.URL)
+	if err != nil {
+		return false
+	}
+	loc, err := from.Parse(hop.Location)
+	if err != nil {
+		return false
+	}
+	if loc.String() == exp {
+		return true
+	}
+	return strings.HasPrefix(exp, "/") &&
+		strings.EqualFold(loc.Host, from.Host) &&
+		loc.RequestURI() == exp
+}
+
+// checkRedirects returns nil if the redirects followed to arrive at the
+// supplied HTTP response satisfy the supplied assertions, one per redirect,
+// otherwise it returns the first failure.
+func checkRedirects(
+	ctx context.Context,
+	exps []*RedirectExpect,
+	resp *nethttp.Response,
+) error {
+	hops := redirectChain(resp)
+	if len(hops) != len(exps) {
+		return HTTPRedirectCountNotEqual(len(exps), hops)
+	}
+	for x, exp := range exps {
+		if err := exp.check(ctx, x+1, hops[x]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
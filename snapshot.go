@@ -0,0 +1,160 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gdt-dev/core/debug"
+	"github.com/theory/jsonpath"
+	"github.com/theory/jsonpath/spec"
+)
+
+// EnvUpdateSnapshots is the environment variable that, when set to a true
+// value such as "1" or "true", causes snapshot assertions to rewrite their
+// snapshot files instead of failing when the HTTP response differs.
+const EnvUpdateSnapshots = "GDT_UPDATE_SNAPSHOTS"
+
+// snapshotIgnored replaces the values of ignored JSONPath expressions before
+// comparing a snapshot with an HTTP response.
+const snapshotIgnored = "<ignored>"
+
+var updateSnapshots atomic.Bool
+
+// SetUpdateSnapshots sets whether snapshot assertions rewrite their snapshot
+// files instead of failing when the HTTP response differs. This is typically
+// wired to a command-line flag in the test binary's TestMain:
+//
+//	var update = flag.Bool("update", false, "update snapshots")
+//
+//	func TestMain(m *testing.M) {
+//	    flag.Parse()
+//	    gdthttp.SetUpdateSnapshots(*update)
+//	    os.Exit(m.Run())
+//	}
+//
+// Snapshots are also updated when the GDT_UPDATE_SNAPSHOTS environment
+// variable is true.
+func SetUpdateSnapshots(update bool) {
+	updateSnapshots.Store(update)
+}
+
+// updatingSnapshots returns true if snapshot files should be rewritten.
+func updatingSnapshots() bool {
+	if updateSnapshots.Load() {
+		return true
+	}
+	update, _ := strconv.ParseBool(os.Getenv(EnvUpdateSnapshots))
+	return update
+}
+
+// SnapshotExpect describes an assertion that the HTTP response body equals a
+// previously recorded snapshot of the HTTP response body.
+type SnapshotExpect struct {
+	// File is the path to the snapshot file. Relative paths are resolved
+	// relative to the test scenario file.
+	File string `yaml:"file"`
+	// Ignore is a list of JSONPath expressions identifying volatile values,
+	// such as generated identifiers and timestamps, that are ignored when
+	// comparing a JSON HTTP response body with the snapshot.
+	Ignore []string `yaml:"ignore,omitempty"`
+	// ignore contains the parsed Ignore JSONPath expressions.
+	ignore []*jsonpath.Path
+}
+
+// check returns nil if the supplied HTTP response body equals the snapshot,
+// otherwise it returns the failure. When updating snapshots, a missing or
+// differing snapshot is rewritten instead.
+func (e *SnapshotExpect) check(ctx context.Context, b []byte) error {
+	snap, err := os.ReadFile(e.File)
+	if errors.Is(err, fs.ErrNotExist) {
+		if updatingSnapshots() {
+			return e.write(ctx, b)
+		}
+		return HTTPSnapshotMissing(e.File)
+	}
+	if err != nil {
+		return err
+	}
+	exp := e.normalize(snap)
+	got := e.normalize(b)
+	if exp == got {
+		return nil
+	}
+	if updatingSnapshots() {
+		return e.write(ctx, b)
+	}
+	return HTTPBodyNotEqual(e.File, exp, got)
+}
+
+// normalize returns the string form of the supplied content that is compared
+// with the snapshot. JSON content is indented consistently and has the values
+// of any ignored JSONPath expressions replaced. Other content is returned
+// as-is.
+func (e *SnapshotExpect) normalize(b []byte) string {
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return string(b)
+	}
+	for _, p := range e.ignore {
+		for _, n := range p.SelectLocated(doc) {
+			doc = replaceAt(doc, n.Path, snapshotIgnored)
+		}
+	}
+	return indentJSON(doc)
+}
+
+// write records the supplied HTTP response body as the snapshot. JSON content
+// is indented to make the snapshot file easy to review.
+func (e *SnapshotExpect) write(ctx context.Context, b []byte) error {
+	var doc any
+	if err := json.Unmarshal(b, &doc); err == nil {
+		b = []byte(indentJSON(doc))
+	}
+	if err := os.MkdirAll(filepath.Dir(e.File), 0o755); err != nil {
+		return err
+	}
+	debug.Printf(ctx, "http: updating snapshot %s", e.File)
+	return os.WriteFile(e.File, b, 0o644)
+}
+
+// indentJSON returns the indented JSON encoding of the supplied document
+// with a trailing newline.
+func indentJSON(doc any) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	// The document was decoded from JSON so it can always be encoded.
+	_ = enc.Encode(doc)
+	return buf.String()
+}
+
+// replaceAt returns the supplied document with the value at the supplied
+// normalized path replaced with val.
+func replaceAt(doc any, path spec.NormalizedPath, val any) any {
+	if len(path) == 0 {
+		return val
+	}
+	switch sel := path[0].(type) {
+	case spec.Name:
+		if m, ok := doc.(map[string]any); ok {
+			m[string(sel)] = replaceAt(m[string(sel)], path[1:], val)
+		}
+	case spec.Index:
+		if l, ok := doc.([]any); ok && int(sel) < len(l) {
+			l[sel] = replaceAt(l[sel], path[1:], val)
+		}
+	}
+	return doc
+}
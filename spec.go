@@ -6,6 +6,7 @@ package http
 
 import (
 	"github.com/gdt-dev/core/api"
+	"github.com/samber/lo"
 )
 
 // HTTPSpec is the complex type containing all of the HTTP-specific actions.
@@ -35,8 +36,28 @@ type Spec struct {
 	PATCH string `yaml:"PATCH,omitempty"`
 	// Shortcut for `http.delete`
 	DELETE string `yaml:"DELETE,omitempty"`
+	// Shortcut for `http.head`
+	HEAD string `yaml:"HEAD,omitempty"`
+	// Shortcut for `http.options`
+	OPTIONS string `yaml:"OPTIONS,omitempty"`
 	// Shortcut for `http.data`
 	Data any `yaml:"data,omitempty"`
+	// Shortcut for `http.headers`
+	Headers map[string][]string `yaml:"headers,omitempty"`
+	// Shortcut for `http.query`
+	Query map[string][]string `yaml:"query,omitempty"`
+	// Shortcut for `http.body`
+	Body *Body `yaml:"body,omitempty"`
+	// Shortcut for `http.data_file`
+	DataFile string `yaml:"data_file,omitempty"`
+	// Shortcut for `http.body_file`
+	BodyFile string `yaml:"body_file,omitempty"`
+	// Shortcut for `http.auth`
+	Auth *Auth `yaml:"auth,omitempty"`
+	// Shortcut for `http.cookies`
+	Cookies map[string]string `yaml:"cookies,omitempty"`
+	// Shortcut for `http.follow_redirects`
+	FollowRedirects *RedirectPolicy `yaml:"follow_redirects,omitempty"`
 	// Assert is the assertions for the HTTP response
 	Assert *Expect `yaml:"assert,omitempty"`
 	// Var allows the test author to save arbitrary data to the test scenario,
@@ -60,11 +81,12 @@ func (s *Spec) Retry() *api.Retry {
 		// The user may have overridden in the test spec file...
 		return s.Spec.Retry
 	}
-	if s.Method == "GET" {
+	if s.HTTP != nil && lo.Contains(idempotentHTTPMethods, s.HTTP.Method) {
 		// returning nil here means the plugin's default will be used...
 		return nil
 	}
-	// for POST/PUT/DELETE/PATCH, we don't want to retry...
+	// for POST/PATCH and extension methods, which are not idempotent, we
+	// don't want to retry...
 	return api.NoRetry
 }
 
@@ -0,0 +1,135 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+package http
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	minStatusCode = 100
+	maxStatusCode = 599
+)
+
+// Status describes the HTTP status codes expected in an HTTP response. The
+// status code must fall within one of the In ranges, if any are specified, and
+// must not fall within any of the Not ranges.
+type Status struct {
+	// In is the list of ranges of HTTP status codes, one of which the status
+	// code must fall within.
+	In []StatusRange `yaml:"-"`
+	// Not is the list of ranges of HTTP status codes that the status code must
+	// not fall within.
+	Not []StatusRange `yaml:"-"`
+}
+
+// StatusRange is an inclusive range of HTTP status codes. A single HTTP
+// status code is a range with equal Min and Max.
+type StatusRange struct {
+	Min int
+	Max int
+}
+
+// StatusCode returns a Status expecting the supplied HTTP status code.
+func StatusCode(code int) *Status {
+	return &Status{In: []StatusRange{{Min: code, Max: code}}}
+}
+
+// Contains returns true if the supplied HTTP status code falls within the
+// range.
+func (r StatusRange) Contains(code int) bool {
+	return code >= r.Min && code <= r.Max
+}
+
+// String returns the range as a single status code (`200`), a status class
+// (`2xx`) or a range of status codes (`200-204`).
+func (r StatusRange) String() string {
+	switch {
+	case r.Min == r.Max:
+		return strconv.Itoa(r.Min)
+	case r.Min%100 == 0 && r.Max == r.Min+99:
+		return strconv.Itoa(r.Min/100) + "xx"
+	}
+	return strconv.Itoa(r.Min) + "-" + strconv.Itoa(r.Max)
+}
+
+// Matches returns true if the supplied HTTP status code is expected.
+func (s *Status) Matches(code int) bool {
+	if len(s.In) > 0 && !statusRangesContain(s.In, code) {
+		return false
+	}
+	return !statusRangesContain(s.Not, code)
+}
+
+// String describes the expected HTTP status codes, e.g. `200`,
+// `one of 200, 204` or `not 5xx`.
+func (s *Status) String() string {
+	parts := []string{}
+	switch len(s.In) {
+	case 0:
+	case 1:
+		parts = append(parts, s.In[0].String())
+	default:
+		parts = append(parts, "one of "+joinStatusRanges(s.In))
+	}
+	if len(s.Not) > 0 {
+		parts = append(parts, "not "+joinStatusRanges(s.Not))
+	}
+	return strings.Join(parts, " and ")
+}
+
+// parseStatusRange parses a single HTTP status code (`200`), a status class
+// (`2xx`) or a range of status codes (`200-299`), returning false if the
+// supplied string is not one of these or is out of the range of valid HTTP
+// status codes.
+func parseStatusRange(s string) (StatusRange, bool) {
+	s = strings.TrimSpace(s)
+	var r StatusRange
+	if class, found := strings.CutSuffix(strings.ToLower(s), "xx"); found {
+		c, err := strconv.Atoi(class)
+		if err != nil || len(class) != 1 {
+			return r, false
+		}
+		r = StatusRange{Min: c * 100, Max: c*100 + 99}
+	} else if first, last, found := strings.Cut(s, "-"); found {
+		min, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil {
+			return r, false
+		}
+		max, err := strconv.Atoi(strings.TrimSpace(last))
+		if err != nil {
+			return r, false
+		}
+		r = StatusRange{Min: min, Max: max}
+	} else {
+		code, err := strconv.Atoi(s)
+		if err != nil {
+			return r, false
+		}
+		r = StatusRange{Min: code, Max: code}
+	}
+	if r.Min < minStatusCode || r.Max > maxStatusCode || r.Min > r.Max {
+		return r, false
+	}
+	return r, true
+}
+
+func statusRangesContain(ranges []StatusRange, code int) bool {
+	for _, r := range ranges {
+		if r.Contains(code) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinStatusRanges(ranges []StatusRange) string {
+	strs := make([]string, len(ranges))
+	for x, r := range ranges {
+		strs[x] = r.String()
+	}
+	return strings.Join(strs, ", ")
+}
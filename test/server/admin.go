@@ -0,0 +1,88 @@
+package server
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+var adminBooksTemplate = template.Must(template.New("books").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <title>Books - Admin</title>
+</head>
+<body>
+  <h1>Books</h1>
+  {{- if .Flash}}
+  <p class="flash">{{.Flash}}</p>
+  {{- end}}
+  <table id="books">
+    <tbody>
+      {{- range .Books}}
+      <tr data-id="{{.ID}}">
+        <td class="title">{{.Title}}</td>
+        <td class="author">{{if .Author}}{{.Author.Name}}{{end}}</td>
+        <td class="pages">{{.Pages}}</td>
+      </tr>
+      {{- end}}
+    </tbody>
+  </table>
+  <form id="add-book" method="post" action="/admin/books">
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+    <input type="text" name="title">
+    <button type="submit">Add book</button>
+  </form>
+</body>
+</html>
+`))
+
+// adminBooksPage is the data rendered by adminBooksTemplate
+type adminBooksPage struct {
+	Flash     string
+	Books     []*Book
+	CSRFToken string
+}
+
+// handleAdminBooks renders a server-side HTML page listing the books with a
+// form, protected by a single-use CSRF token, for adding a book.
+func handleAdminBooks(s *server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := adminBooksPage{}
+		status := http.StatusOK
+		switch r.Method {
+		case "GET":
+		case "POST":
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			token := r.PostForm.Get("csrf_token")
+			s.Lock()
+			valid := s.csrfTokens[token]
+			delete(s.csrfTokens, token)
+			s.Unlock()
+			if !valid {
+				http.Error(w, "invalid CSRF token", http.StatusForbidden)
+				return
+			}
+			page.Flash = "Added " + r.PostForm.Get("title")
+			status = http.StatusCreated
+		default:
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		page.CSRFToken = uuid.New().String()
+		s.Lock()
+		s.csrfTokens[page.CSRFToken] = true
+		page.Books = s.listBooks()
+		s.Unlock()
+		sort.Slice(page.Books, func(i, j int) bool {
+			return page.Books[i].Title < page.Books[j].Title
+		})
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		w.WriteHeader(status)
+		adminBooksTemplate.Execute(w, &page)
+	})
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"encoding/xml"
+	"net/http"
+	"sort"
+)
+
+// handleCatalog returns the list of books as an XML document sorted by title
+func handleCatalog(s *server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		s.Lock()
+		books := s.listBooks()
+		s.Unlock()
+		sort.Slice(books, func(i, j int) bool {
+			return books[i].Title < books[j].Title
+		})
+		catalog := Catalog{Books: make([]*CatalogBook, 0, len(books))}
+		for _, book := range books {
+			cb := &CatalogBook{
+				ID:    book.ID,
+				Title: book.Title,
+				Pages: book.Pages,
+			}
+			if book.Author != nil {
+				cb.Author = book.Author.Name
+			}
+			catalog.Books = append(catalog.Books, cb)
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(xml.Header))
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		enc.Encode(&catalog)
+	})
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeYAML writes the supplied value as a YAML document with the supplied
+// Content-Type. The YAML document has the same field names as the JSON
+// encoding of the value.
+func writeYAML(w http.ResponseWriter, contentType string, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	yaml.NewEncoder(w).Encode(doc)
+}
+
+// writeNDJSON writes each of the supplied books as a JSON document on its own
+// line.
+func writeNDJSON(w http.ResponseWriter, books []*Book) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	for _, book := range books {
+		enc.Encode(book)
+	}
+}
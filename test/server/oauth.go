@@ -0,0 +1,113 @@
+// A super simple OAuth2 token endpoint for use in testing.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+)
+
+// oauthClients maps the client IDs allowed to request access tokens to the
+// client's secret.
+var oauthClients = map[string]string{
+	"gdt": "s3:cr3t%+",
+}
+
+// accessTokenTTL is the number of seconds issued access tokens are valid for.
+const accessTokenTTL = 3600
+
+// handleOAuthToken issues access tokens using the OAuth2 client credentials
+// grant. Client credentials may be sent using HTTP Basic authentication or in
+// the client_id and client_secret form fields. As required by RFC 6749,
+// credentials sent using HTTP Basic authentication are form-urlencoded.
+func handleOAuthToken(s *server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if r.PostForm.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(&OAuthErrorResponse{
+				Error: "unsupported_grant_type",
+			})
+			return
+		}
+		clientID, clientSecret, ok := r.BasicAuth()
+		if ok {
+			var idErr, secretErr error
+			clientID, idErr = url.QueryUnescape(clientID)
+			clientSecret, secretErr = url.QueryUnescape(clientSecret)
+			if idErr != nil || secretErr != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(&OAuthErrorResponse{
+					Error: "invalid_client",
+				})
+				return
+			}
+		} else {
+			clientID = r.PostForm.Get("client_id")
+			clientSecret = r.PostForm.Get("client_secret")
+		}
+		secret, found := oauthClients[clientID]
+		if !found || secret != clientSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(&OAuthErrorResponse{
+				Error: "invalid_client",
+			})
+			return
+		}
+		token := uuid.New().String()
+		s.Lock()
+		s.accessTokens[token] = clientID
+		s.tokensIssued++
+		s.Unlock()
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(&OAuthTokenResponse{
+			AccessToken: token,
+			TokenType:   "Bearer",
+			ExpiresIn:   accessTokenTTL,
+			Scope:       r.PostForm.Get("scope"),
+		})
+	})
+}
+
+// handleOAuthTokenMoved permanently redirects token requests to the
+// /oauth/token endpoint, preserving the request method and form.
+func handleOAuthTokenMoved(s *server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/oauth/token", http.StatusPermanentRedirect)
+	})
+}
+
+// handleOAuthTokens returns the number of access tokens issued on GET and
+// revokes all issued access tokens on DELETE.
+func handleOAuthTokens(s *server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			s.Lock()
+			issued := s.tokensIssued
+			s.Unlock()
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(&OAuthTokensResponse{Issued: issued})
+		case "DELETE":
+			s.Lock()
+			s.accessTokens = map[string]string{}
+			s.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		}
+	})
+}
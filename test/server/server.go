@@ -5,8 +5,11 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
 
@@ -27,14 +30,26 @@ type server struct {
 	authors    map[string]*Author
 	publishers map[string]*Publisher
 	books      map[string]*Book
+	// sessions maps session tokens to the username that logged in
+	sessions map[string]string
+	// accessTokens maps OAuth2 access tokens to the client ID the token was
+	// issued to
+	accessTokens map[string]string
+	// tokensIssued is the number of OAuth2 access tokens issued
+	tokensIssued int
+	// csrfTokens contains the unused CSRF tokens rendered in admin forms
+	csrfTokens map[string]bool
 }
 
 func NewController(logger *log.Logger) *server {
 	return &server{
-		logger:     logger,
-		authors:    map[string]*Author{},
-		publishers: map[string]*Publisher{},
-		books:      map[string]*Book{},
+		logger:       logger,
+		authors:      map[string]*Author{},
+		publishers:   map[string]*Publisher{},
+		books:        map[string]*Book{},
+		sessions:     map[string]string{},
+		accessTokens: map[string]string{},
+		csrfTokens:   map[string]bool{},
 	}
 }
 
@@ -60,10 +75,13 @@ func NewControllerWithBooks(logger *log.Logger, data []*Book) *server {
 		books[book.ID] = book
 	}
 	return &server{
-		logger:     logger,
-		authors:    authors,
-		publishers: publishers,
-		books:      books,
+		logger:       logger,
+		authors:      authors,
+		publishers:   publishers,
+		books:        books,
+		sessions:     map[string]string{},
+		accessTokens: map[string]string{},
+		csrfTokens:   map[string]bool{},
 	}
 }
 
@@ -71,6 +89,24 @@ func (s *server) Router() http.Handler {
 	router := http.NewServeMux()
 	router.Handle("/books/", handleBook(s))
 	router.Handle("/books", handleBooks(s))
+	router.Handle("/admin/books", handleAdminBooks(s))
+	router.Handle("/authors", handleAuthors(s))
+	router.Handle("/authors/count", handleAuthorsCount(s))
+	router.Handle("/catalog", handleCatalog(s))
+	router.Handle("/echo", handleEcho(s))
+	router.Handle("/headers", handleHeaders(s))
+	router.Handle("/login", handleLogin(s))
+	router.Handle("/logout", handleLogout(s))
+	router.Handle("/whoami", handleWhoami(s))
+	router.Handle("/oauth/token", handleOAuthToken(s))
+	router.Handle("/oauth/token-moved", handleOAuthTokenMoved(s))
+	router.Handle("/oauth/tokens", handleOAuthTokens(s))
+	router.Handle("/sso/start", handleSSOStart(s))
+	router.Handle("/sso/authorize", handleSSOAuthorize(s))
+	router.Handle("/sso/callback", handleSSOCallback(s))
+	router.Handle("/slow", handleSlow(s))
+	router.Handle("/jwt/token", handleJWTToken(s))
+	router.Handle("/.well-known/jwks.json", handleJWKS(s))
 	return router
 }
 
@@ -83,9 +119,13 @@ func handleBooks(s *server) http.Handler {
 		case "PUT":
 			putBooks(s, w, r)
 			return
-		case "GET":
+		case "GET", "HEAD":
 			listBooks(s, w, r)
 			return
+		case "OPTIONS":
+			w.Header().Set("Allow", "GET, HEAD, OPTIONS, POST, PUT")
+			w.WriteHeader(http.StatusNoContent)
+			return
 		}
 		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 		return
@@ -105,6 +145,224 @@ func handleBook(s *server) http.Handler {
 	})
 }
 
+// handleAuthors returns the list of authors as a JSON array sorted by name
+func handleAuthors(s *server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Lock()
+		authors := make([]*Author, 0, len(s.authors))
+		for _, author := range s.authors {
+			authors = append(authors, author)
+		}
+		s.Unlock()
+		sort.Slice(authors, func(i, j int) bool {
+			return authors[i].Name < authors[j].Name
+		})
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(authors)
+	})
+}
+
+// handleAuthorsCount returns the number of authors as a bare JSON number
+func handleAuthorsCount(s *server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Lock()
+		count := len(s.authors)
+		s.Unlock()
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(count)
+	})
+}
+
+// handleEcho returns a JSON document describing the HTTP request that was
+// received. It allows tests to verify what the HTTP client actually sent.
+func handleEcho(s *server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		er := EchoResponse{
+			Method:  r.Method,
+			Path:    r.URL.Path,
+			Headers: r.Header,
+			Query:   r.URL.Query(),
+		}
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "application/x-www-form-urlencoded":
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			er.Form = r.PostForm
+		case "multipart/form-data":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			er.Form = r.MultipartForm.Value
+			er.Files = map[string]*EchoFile{}
+			for name, fhs := range r.MultipartForm.File {
+				fh := fhs[0]
+				f, err := fh.Open()
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				contents, _ := io.ReadAll(f)
+				f.Close()
+				er.Files[name] = &EchoFile{
+					Filename:    fh.Filename,
+					ContentType: fh.Header.Get("Content-Type"),
+					Contents:    string(contents),
+				}
+			}
+		default:
+			body, _ := io.ReadAll(r.Body)
+			er.Body = string(body)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(&er)
+	})
+}
+
+// handleHeaders responds with an HTTP header for each query parameter in the
+// request, using the query parameter's name as the header name and the query
+// parameter's values as the header values.
+func handleHeaders(s *server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for key, vals := range r.URL.Query() {
+			for _, val := range vals {
+				w.Header().Add(key, val)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// handleLogin accepts a form with username and password fields. Any username
+// with the password "password" is accepted. On success, a session token is
+// returned in the response body, the X-Auth-Token header and the "session"
+// cookie. A long-lived "remember_me" cookie is also set.
+func handleLogin(s *server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		username := r.PostForm.Get("username")
+		if username == "" || r.PostForm.Get("password") != "password" {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		token := uuid.New().String()
+		s.Lock()
+		s.sessions[token] = username
+		s.Unlock()
+		http.SetCookie(w, &http.Cookie{
+			Name:     "session",
+			Value:    token,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.SetCookie(w, &http.Cookie{
+			Name:     "remember_me",
+			Value:    username,
+			Path:     "/login",
+			MaxAge:   30 * 24 * 60 * 60,
+			Secure:   true,
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+		w.Header().Set("X-Auth-Token", token)
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(&LoginResponse{
+			Username: username,
+			Token:    token,
+		})
+	})
+}
+
+// handleLogout ends the session in the "session" cookie and deletes the
+// cookie.
+func handleLogout(s *server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		if c, err := r.Cookie("session"); err == nil {
+			s.Lock()
+			delete(s.sessions, c.Value)
+			s.Unlock()
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     "session",
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// handleWhoami returns the username associated with the credentials in the
+// request. See `server.authenticate`.
+func handleWhoami(s *server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username := s.authenticate(r)
+		if username == "" {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(&WhoamiResponse{Username: username})
+	})
+}
+
+// apiKeys maps the API keys accepted in the X-API-Key header or the api_key
+// query parameter to the username the key belongs to.
+var apiKeys = map[string]string{
+	"s3cr3t-api-key": "robot",
+}
+
+// authenticate returns the username associated with the request's
+// credentials, or the empty string if the request has no valid credentials.
+func (s *server) authenticate(r *http.Request) string {
+	s.Lock()
+	defer s.Unlock()
+	if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		if clientID, found := s.accessTokens[token]; found {
+			return clientID
+		}
+		return s.sessions[token]
+	}
+	if username, password, ok := r.BasicAuth(); ok {
+		if username != "" && password == "password" {
+			return username
+		}
+		return ""
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return apiKeys[key]
+	}
+	if key := r.URL.Query().Get("api_key"); key != "" {
+		return apiKeys[key]
+	}
+	if c, err := r.Cookie("session"); err == nil {
+		return s.sessions[c.Value]
+	}
+	return ""
+}
+
 func getBook(
 	s *server,
 	w http.ResponseWriter,
@@ -138,6 +396,18 @@ func listBooks(s *server, w http.ResponseWriter, r *http.Request) {
 	}
 	var lbr ListBooksResponse
 	lbr.Books = s.listBooks()
+	switch r.Header.Get("Accept") {
+	case "application/yaml":
+		writeYAML(w, "application/yaml", &lbr)
+		return
+	case "text/plain":
+		// Some legacy services return YAML without saying so...
+		writeYAML(w, "text/plain; charset=UTF-8", &lbr)
+		return
+	case "application/x-ndjson":
+		writeNDJSON(w, lbr.Books)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(&lbr)
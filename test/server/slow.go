@@ -0,0 +1,28 @@
+// A slow endpoint that redirects to itself, for use in testing latency.
+
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// slowDelay is how long the slow endpoint waits before responding.
+const slowDelay = 50 * time.Millisecond
+
+// handleSlow waits for slowDelay and then, if the hops query parameter is
+// greater than zero, redirects to itself with one fewer hop. Otherwise it
+// responds with no content.
+func handleSlow(s *server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(slowDelay)
+		hops, _ := strconv.Atoi(r.URL.Query().Get("hops"))
+		if hops > 0 {
+			next := "/slow?hops=" + strconv.Itoa(hops-1)
+			http.Redirect(w, r, next, http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// ssoState is the state parameter passed through the single sign-on redirects
+const ssoState = "xyz"
+
+// handleSSOStart begins a single sign-on by redirecting to the authorization
+// endpoint with a relative Location.
+func handleSSOStart(s *server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := url.Values{}
+		q.Set("state", ssoState)
+		q.Set("redirect_uri", "/sso/callback")
+		http.Redirect(w, r, "/sso/authorize?"+q.Encode(), http.StatusFound)
+	})
+}
+
+// handleSSOAuthorize redirects to the redirect_uri with an authorization code
+// and the state, using an absolute Location.
+func handleSSOAuthorize(s *server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirectURI := r.URL.Query().Get("redirect_uri")
+		if redirectURI == "" {
+			http.Error(w, "missing redirect_uri", http.StatusBadRequest)
+			return
+		}
+		q := url.Values{}
+		q.Set("code", "abc123")
+		q.Set("state", r.URL.Query().Get("state"))
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		loc := scheme + "://" + r.Host + redirectURI + "?" + q.Encode()
+		http.Redirect(w, r, loc, http.StatusSeeOther)
+	})
+}
+
+// handleSSOCallback checks the authorization code and state and redirects to
+// the list of books.
+func handleSSOCallback(s *server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("code") != "abc123" || q.Get("state") != ssoState {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		http.Redirect(w, r, "/books", http.StatusFound)
+	})
+}
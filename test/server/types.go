@@ -1,5 +1,7 @@
 package server
 
+import "encoding/xml"
+
 type Author struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
@@ -35,6 +37,21 @@ func (b *Book) CategoryByLength() string {
 	return "NOVEL"
 }
 
+// Catalog is the XML document returned by the /catalog endpoint
+type Catalog struct {
+	XMLName xml.Name       `xml:"catalog"`
+	Books   []*CatalogBook `xml:"book"`
+}
+
+// CatalogBook describes a book in the XML document returned by the /catalog
+// endpoint
+type CatalogBook struct {
+	ID     string `xml:"id,attr"`
+	Title  string `xml:"title"`
+	Author string `xml:"author"`
+	Pages  int    `xml:"pages"`
+}
+
 type CreateBookRequest struct {
 	Title       string `json:"title"`
 	AuthorID    string `json:"author_id"`
@@ -57,3 +74,50 @@ type ReplaceBooksRequest []*ReplaceBookRequest
 type ListBooksResponse struct {
 	Books []*Book `json:"books"`
 }
+
+// EchoResponse describes the HTTP request received by the /echo endpoint
+type EchoResponse struct {
+	Method  string               `json:"method"`
+	Path    string               `json:"path"`
+	Headers map[string][]string  `json:"headers"`
+	Query   map[string][]string  `json:"query"`
+	Body    string               `json:"body"`
+	Form    map[string][]string  `json:"form,omitempty"`
+	Files   map[string]*EchoFile `json:"files,omitempty"`
+}
+
+// EchoFile describes a file received in a multipart/form-data request
+type EchoFile struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Contents    string `json:"contents"`
+}
+
+type LoginResponse struct {
+	Username string `json:"username"`
+	Token    string `json:"token"`
+}
+
+type WhoamiResponse struct {
+	Username string `json:"username"`
+}
+
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+type OAuthErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type OAuthTokensResponse struct {
+	Issued int `json:"issued"`
+}
+
+// JWTTokenResponse is returned by the /jwt/token endpoint
+type JWTTokenResponse struct {
+	IDToken string `json:"id_token"`
+}
@@ -0,0 +1,193 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"strings"
+	"time"
+)
+
+const (
+	// LatencyDNS is the time spent resolving the server's host name.
+	LatencyDNS = "dns"
+	// LatencyConnect is the time spent establishing the TCP connection.
+	LatencyConnect = "connect"
+	// LatencyTLS is the time spent on the TLS handshake.
+	LatencyTLS = "tls"
+	// LatencyTTFB is the time from sending the HTTP request until the first
+	// byte of the HTTP response was received.
+	LatencyTTFB = "ttfb"
+	// LatencyTotal is the time from starting the HTTP request until the HTTP
+	// response body was completely read.
+	LatencyTotal = "total"
+)
+
+var validLatencyPhases = []string{
+	LatencyDNS,
+	LatencyConnect,
+	LatencyTLS,
+	LatencyTTFB,
+	LatencyTotal,
+}
+
+// Timings contains the time spent in each phase of an HTTP request. Phases
+// that did not occur, for instance DNS resolution and connecting when an idle
+// connection is reused, are zero. When redirects are followed, the time spent
+// in each phase is the sum of the time spent in that phase by each HTTP
+// request in the redirect chain, so that, like Total, the phases cover the
+// whole chain.
+type Timings struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration
+	Total   time.Duration
+
+	start      time.Time
+	dnsStart   time.Time
+	connStart  time.Time
+	tlsStart   time.Time
+	wroteStart time.Time
+}
+
+// withTrace resets the timings and returns a context that records the
+// timings of the HTTP request made with it, including any redirects that are
+// followed.
+func (t *Timings) withTrace(ctx context.Context) context.Context {
+	*t = Timings{start: time.Now()}
+	t.wroteStart = t.start
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.DNS += time.Since(t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.connStart = time.Now()
+		},
+		ConnectDone: func(string, string, error) {
+			t.Connect += time.Since(t.connStart)
+		},
+		TLSHandshakeStart: func() {
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.TLS += time.Since(t.tlsStart)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.wroteStart = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			t.TTFB += time.Since(t.wroteStart)
+		},
+	})
+}
+
+// done records the total time spent on the HTTP request. It is called once
+// the HTTP response body has been read.
+func (t *Timings) done() {
+	t.Total = time.Since(t.start)
+}
+
+// Phase returns the time spent in the named phase of the HTTP request.
+func (t *Timings) Phase(phase string) time.Duration {
+	switch strings.ToLower(phase) {
+	case LatencyDNS:
+		return t.DNS
+	case LatencyConnect:
+		return t.Connect
+	case LatencyTLS:
+		return t.TLS
+	case LatencyTTFB:
+		return t.TTFB
+	}
+	return t.Total
+}
+
+// LatencyExpect contains assertions about the time spent in each phase of the
+// HTTP request.
+type LatencyExpect struct {
+	DNS     *DurationCondition `yaml:"dns,omitempty"`
+	Connect *DurationCondition `yaml:"connect,omitempty"`
+	TLS     *DurationCondition `yaml:"tls,omitempty"`
+	TTFB    *DurationCondition `yaml:"ttfb,omitempty"`
+	Total   *DurationCondition `yaml:"total,omitempty"`
+}
+
+// check returns nil if the supplied timings satisfy all of the assertions,
+// otherwise it returns the first failure.
+func (e *LatencyExpect) check(t *Timings) error {
+	conds := []struct {
+		phase string
+		cond  *DurationCondition
+	}{
+		{LatencyDNS, e.DNS},
+		{LatencyConnect, e.Connect},
+		{LatencyTLS, e.TLS},
+		{LatencyTTFB, e.TTFB},
+		{LatencyTotal, e.Total},
+	}
+	for _, c := range conds {
+		if c.cond == nil {
+			continue
+		}
+		got := t.Phase(c.phase)
+		if !c.cond.Matches(got) {
+			return HTTPLatencyNotMatched(c.phase, c.cond, got)
+		}
+	}
+	return nil
+}
+
+// DurationCondition compares a duration with a bound, e.g. `<200ms`.
+type DurationCondition struct {
+	// Op is one of "<", "<=", ">" or ">=".
+	Op string
+	// Duration is the bound the duration is compared with.
+	Duration time.Duration
+}
+
+// Matches returns true if the supplied duration satisfies the condition.
+func (c *DurationCondition) Matches(d time.Duration) bool {
+	switch c.Op {
+	case "<":
+		return d < c.Duration
+	case ">":
+		return d > c.Duration
+	case ">=":
+		return d >= c.Duration
+	}
+	return d <= c.Duration
+}
+
+// String returns the condition in the form it is written in test specs, e.g.
+// `<200ms`.
+func (c *DurationCondition) String() string {
+	return c.Op + c.Duration.String()
+}
+
+// parseDurationCondition parses a duration condition of the form `<200ms`. If
+// no comparison operator is specified, the duration is an upper bound as if
+// written `<=200ms`.
+func parseDurationCondition(s string) (*DurationCondition, error) {
+	s = strings.TrimSpace(s)
+	op := "<="
+	for _, candidate := range []string{"<=", ">=", "<", ">"} {
+		if rest, found := strings.CutPrefix(s, candidate); found {
+			op = candidate
+			s = strings.TrimSpace(rest)
+			break
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, err
+	}
+	return &DurationCondition{Op: op, Duration: d}, nil
+}
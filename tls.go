@@ -0,0 +1,330 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	nethttp "net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tlsVersions maps the TLS versions accepted in `min_version` to their
+// crypto/tls constants.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig describes the TLS configuration of the HTTP client used to send
+// HTTP requests.
+type TLSConfig struct {
+	// CAFile is the path to a file containing one or more PEM-encoded CA
+	// certificates that are trusted in addition to the system's trusted CA
+	// certificates when verifying the server's certificate. Relative paths
+	// are resolved relative to the test scenario file.
+	CAFile string `yaml:"ca_file,omitempty"`
+	// CertFile is the path to a file containing the PEM-encoded client
+	// certificate presented to servers that request one. Must be specified
+	// with KeyFile.
+	CertFile string `yaml:"cert_file,omitempty"`
+	// KeyFile is the path to a file containing the PEM-encoded private key of
+	// the client certificate. Must be specified with CertFile.
+	KeyFile string `yaml:"key_file,omitempty"`
+	// ServerName is the name the server's certificate is verified against
+	// and that is sent to the server in the SNI extension. Defaults to the
+	// host in the URL of the HTTP request.
+	ServerName string `yaml:"server_name,omitempty"`
+	// MinVersion is the minimum TLS version accepted, one of "1.0", "1.1",
+	// "1.2" or "1.3".
+	MinVersion string `yaml:"min_version,omitempty"`
+	// InsecureSkipVerify, if true, disables verification of the server's
+	// certificate.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty"`
+	// rootCAs contains the certificates in CAFile.
+	rootCAs []*x509.Certificate
+	// cert is the client certificate loaded from CertFile and KeyFile.
+	cert *tls.Certificate
+}
+
+// load reads and validates the CA certificates and client certificate files.
+func (c *TLSConfig) load() error {
+	if c.CAFile != "" {
+		certs, err := readCertificates(c.CAFile)
+		if err != nil {
+			return fmt.Errorf("cannot read ca_file %s: %w", c.CAFile, err)
+		}
+		c.rootCAs = certs
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return fmt.Errorf("cert_file and key_file must be specified together")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return fmt.Errorf("cannot load client certificate: %w", err)
+		}
+		c.cert = &cert
+	}
+	if c.MinVersion != "" {
+		if _, ok := tlsVersions[c.MinVersion]; !ok {
+			return fmt.Errorf(
+				"invalid min_version %q. valid versions: 1.0, 1.1, 1.2, 1.3",
+				c.MinVersion,
+			)
+		}
+	}
+	return nil
+}
+
+// apply returns a copy of the supplied crypto/tls Config, which may be nil,
+// with the TLS configuration applied.
+func (c *TLSConfig) apply(base *tls.Config) *tls.Config {
+	cfg := &tls.Config{}
+	if base != nil {
+		cfg = base.Clone()
+	}
+	if len(c.rootCAs) > 0 {
+		pool := cfg.RootCAs
+		if pool != nil {
+			pool = pool.Clone()
+		} else if sys, err := x509.SystemCertPool(); err == nil {
+			pool = sys
+		} else {
+			pool = x509.NewCertPool()
+		}
+		for _, cert := range c.rootCAs {
+			pool.AddCert(cert)
+		}
+		cfg.RootCAs = pool
+	}
+	if c.cert != nil {
+		cfg.Certificates = []tls.Certificate{*c.cert}
+	}
+	if c.ServerName != "" {
+		cfg.ServerName = c.ServerName
+	}
+	if c.MinVersion != "" {
+		cfg.MinVersion = tlsVersions[c.MinVersion]
+	}
+	if c.InsecureSkipVerify {
+		cfg.InsecureSkipVerify = true
+	}
+	return cfg
+}
+
+// transport returns a copy of the supplied HTTP transport, which may be nil,
+// with the TLS configuration applied. If the supplied transport is not a
+// *net/http.Transport, a copy of net/http.DefaultTransport is used instead.
+func (c *TLSConfig) transport(base nethttp.RoundTripper) *nethttp.Transport {
+	t, ok := base.(*nethttp.Transport)
+	if !ok {
+		t = nethttp.DefaultTransport.(*nethttp.Transport)
+	}
+	t = t.Clone()
+	t.TLSClientConfig = c.apply(t.TLSClientConfig)
+	return t
+}
+
+// readCertificates returns the PEM-encoded certificates in the supplied file.
+func readCertificates(path string) ([]*x509.Certificate, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	certs := []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, contents = pem.Decode(contents)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM-encoded certificates found")
+	}
+	return certs, nil
+}
+
+// TLSExpect contains assertions about the TLS connection the HTTP response
+// was received on and the certificate presented by the server.
+type TLSExpect struct {
+	// Version is a list of TLS versions, one of which must have been
+	// negotiated, e.g. "1.3".
+	Version []string `yaml:"version,omitempty"`
+	// CipherSuite is a list of cipher suite names, one of which must have
+	// been negotiated, e.g. "TLS_AES_128_GCM_SHA256".
+	CipherSuite []string `yaml:"cipher_suite,omitempty"`
+	// ALPN is the application protocol that must have been negotiated using
+	// ALPN, e.g. "h2" or "http/1.1".
+	ALPN *string `yaml:"alpn,omitempty"`
+	// Subject is the expected subject of the server's certificate, either
+	// its common name or its full distinguished name, e.g.
+	// "CN=books.internal,O=gdt-http".
+	Subject string `yaml:"subject,omitempty"`
+	// Issuer is the expected issuer of the server's certificate, either its
+	// common name or its full distinguished name.
+	Issuer string `yaml:"issuer,omitempty"`
+	// SANs is a list of DNS names, IP addresses, email addresses or URIs
+	// that must each be a subject alternative name of the server's
+	// certificate.
+	SANs []string `yaml:"sans,omitempty"`
+	// ExpiresInDays is a condition on the number of days until the server's
+	// certificate expires, e.g. `>=30`.
+	ExpiresInDays string `yaml:"expires_in_days,omitempty"`
+	// expiry is the parsed ExpiresInDays condition.
+	expiry *DurationCondition
+}
+
+// check returns nil if the TLS connection state of the supplied HTTP response
+// satisfies the assertions, otherwise it returns the first failure.
+func (e *TLSExpect) check(state *tls.ConnectionState) error {
+	if state == nil {
+		return HTTPTLSNotUsed()
+	}
+	if len(e.Version) > 0 {
+		got := tlsVersionString(state.Version)
+		if !slices.Contains(e.Version, got) {
+			return HTTPTLSNotMatched(
+				"version", strings.Join(e.Version, " or "), got,
+			)
+		}
+	}
+	if len(e.CipherSuite) > 0 {
+		got := tls.CipherSuiteName(state.CipherSuite)
+		if !slices.Contains(e.CipherSuite, got) {
+			return HTTPTLSNotMatched(
+				"cipher suite", strings.Join(e.CipherSuite, " or "), got,
+			)
+		}
+	}
+	if e.ALPN != nil && state.NegotiatedProtocol != *e.ALPN {
+		return HTTPTLSNotMatched("ALPN protocol", *e.ALPN, state.NegotiatedProtocol)
+	}
+	if e.Subject == "" && e.Issuer == "" && len(e.SANs) == 0 && e.expiry == nil {
+		return nil
+	}
+	if len(state.PeerCertificates) == 0 {
+		return HTTPTLSNotMatched("certificate", "a certificate", "none")
+	}
+	cert := state.PeerCertificates[0]
+	if e.Subject != "" && !nameMatches(cert.Subject, e.Subject) {
+		return HTTPTLSNotMatched(
+			"certificate subject", e.Subject, cert.Subject.String(),
+		)
+	}
+	if e.Issuer != "" && !nameMatches(cert.Issuer, e.Issuer) {
+		return HTTPTLSNotMatched(
+			"certificate issuer", e.Issuer, cert.Issuer.String(),
+		)
+	}
+	if len(e.SANs) > 0 {
+		sans := certificateSANs(cert)
+		for _, san := range e.SANs {
+			if !slices.Contains(sans, san) {
+				return HTTPCertificateSANNotIn(san, sans)
+			}
+		}
+	}
+	if e.expiry != nil {
+		remaining := time.Until(cert.NotAfter)
+		if !e.expiry.Matches(remaining) {
+			return HTTPTLSNotMatched(
+				"certificate expiry",
+				fmt.Sprintf(
+					"%s%d days", e.expiry.Op, int(e.expiry.Duration.Hours()/24),
+				),
+				fmt.Sprintf("%d days", int(remaining.Hours()/24)),
+			)
+		}
+	}
+	return nil
+}
+
+// nameMatches returns true if the supplied expected name is the common name
+// or the full distinguished name of the supplied certificate name.
+func nameMatches(name pkix.Name, exp string) bool {
+	return name.CommonName == exp || name.String() == exp
+}
+
+// certificateSANs returns the subject alternative names of the supplied
+// certificate as strings.
+func certificateSANs(cert *x509.Certificate) []string {
+	sans := slices.Clone(cert.DNSNames)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	return sans
+}
+
+// tlsVersionString returns the version number of the supplied TLS version,
+// e.g. "1.3".
+func tlsVersionString(version uint16) string {
+	for name, v := range tlsVersions {
+		if v == version {
+			return name
+		}
+	}
+	return tls.VersionName(version)
+}
+
+// validCipherSuite returns true if the supplied name is the name of a cipher
+// suite implemented by crypto/tls.
+func validCipherSuite(name string) bool {
+	for _, suites := range [][]*tls.CipherSuite{
+		tls.CipherSuites(), tls.InsecureCipherSuites(),
+	} {
+		for _, suite := range suites {
+			if suite.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseDaysCondition parses a condition on a number of days of the form
+// `>=30`. If no comparison operator is specified, the number of days is a
+// lower bound as if written `>=30`.
+func parseDaysCondition(s string) (*DurationCondition, error) {
+	s = strings.TrimSpace(s)
+	op := ">="
+	for _, candidate := range []string{"<=", ">=", "<", ">"} {
+		if rest, found := strings.CutPrefix(s, candidate); found {
+			op = candidate
+			s = strings.TrimSpace(rest)
+			break
+		}
+	}
+	days, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("expected a number of days but got %q", s)
+	}
+	return &DurationCondition{
+		Op:       op,
+		Duration: time.Duration(days) * 24 * time.Hour,
+	}, nil
+}
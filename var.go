@@ -5,21 +5,73 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	nethttp "net/http"
+	"regexp"
+	"strconv"
+	"time"
 
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
 	"github.com/gdt-dev/core/api"
 	gdtjson "github.com/gdt-dev/core/assertion/json"
+	gdtcontext "github.com/gdt-dev/core/context"
 	"github.com/gdt-dev/core/debug"
 	"github.com/theory/jsonpath"
 )
 
+// varRefRegex matches a `$(name)` variable reference.
+var varRefRegex = regexp.MustCompile(`\$\(([^)]+)\)`)
+
+// VarEntry describes where the value of a variable is extracted from in the
+// HTTP response. Exactly one of From, Header, Status, Latency, Cookie, Regex,
+// XPath or Selector should be set.
 type VarEntry struct {
 	// From is a string that indicates where the value of the variable will be
 	// sourced from. This string is a JSONPath expression that contains
 	// instructions on how to extract a particular field from the HTTP response.
-	From string `yaml:"from"`
+	From string `yaml:"from,omitempty"`
+	// All indicates that all values matched by the From JSONPath expression
+	// are saved as a list. By default, only the first matched value is saved.
+	All bool `yaml:"all,omitempty"`
+	// Header is the name of an HTTP header in the response. The first value
+	// of the header is saved as the variable's value.
+	Header string `yaml:"header,omitempty"`
+	// Status indicates that the response's HTTP status code is saved as the
+	// variable's value.
+	Status bool `yaml:"status,omitempty"`
+	// Cookie is the name of a cookie set by the response. The cookie's value
+	// is saved as the variable's value.
+	Cookie string `yaml:"cookie,omitempty"`
+	// Latency is the name of a phase of the HTTP request, one of "dns",
+	// "connect", "tls", "ttfb" or "total". The time spent in the phase, in
+	// milliseconds, is saved as the variable's value.
+	Latency string `yaml:"latency,omitempty"`
+	// Regex is a regular expression that is matched against the raw HTTP
+	// response body. The text matched by the capture group identified by
+	// Group is saved as the variable's value.
+	Regex string `yaml:"regex,omitempty"`
+	// Group is the index or name of the capture group in Regex whose matched
+	// text is saved as the variable's value. Defaults to the first capture
+	// group, or the entire match if Regex has no capture groups.
+	Group string `yaml:"group,omitempty"`
+	// XPath is an XPath expression that is evaluated against the XML HTTP
+	// response body. The text of the first selected node, or the number,
+	// string or boolean the XPath expression evaluates to, is saved as the
+	// variable's value.
+	XPath string `yaml:"xpath,omitempty"`
+	// Selector is a CSS selector that is matched against the HTML HTTP
+	// response body. The text of the first selected element, or the value of
+	// its attribute named by Attr, is saved as the variable's value.
+	Selector string `yaml:"selector,omitempty"`
+	// Attr is the name of the attribute of the element selected by Selector
+	// whose value is saved as the variable's value.
+	Attr string `yaml:"attr,omitempty"`
 }
 
 // Variables allows the test author to save arbitrary data to the test scenario,
@@ -28,23 +80,65 @@ type VarEntry struct {
 type Variables map[string]VarEntry
 
 // saveVars examines the supplied Variables and what we got back from the
-// Action.Do() call and sets any variables in the run data context key.
+// Action.Do() call and sets any variables in the run data context key. JSONPath
+// expressions are evaluated against the response body decoded from the
+// supplied format.
 func saveVars(
 	ctx context.Context,
 	vars Variables,
+	resp *nethttp.Response,
 	body []byte,
+	format string,
+	timings *Timings,
 	res *api.Result,
 ) error {
-	if len(body) == 0 {
-		return nil
-	}
-	var bodyMap any
-	if err := json.Unmarshal(body, &bodyMap); err != nil {
-		return err
-	}
+	// The response body is only decoded if a variable needs it.
+	var bodyDoc any
+	decoded := false
+	var xmlDoc *xmlquery.Node
+	var htmlDoc *goquery.Document
 	for varName, entry := range vars {
-		path := entry.From
-		extracted, err := extractFrom(path, bodyMap)
+		var extracted any
+		var err error
+		switch {
+		case entry.Header != "":
+			extracted, err = extractHeader(varName, entry.Header, resp)
+		case entry.Status:
+			extracted = resp.StatusCode
+		case entry.Latency != "":
+			extracted = milliseconds(timings.Phase(entry.Latency))
+		case entry.Cookie != "":
+			extracted, err = extractCookie(varName, entry.Cookie, resp)
+		case entry.Regex != "":
+			extracted, err = extractRegex(varName, entry.Regex, entry.Group, body)
+		case entry.XPath != "":
+			if xmlDoc == nil {
+				xmlDoc, err = xmlquery.Parse(bytes.NewReader(body))
+				if err != nil {
+					return VarBodyNotXML(varName, err)
+				}
+			}
+			extracted, err = extractXPath(varName, entry.XPath, xmlDoc)
+		case entry.Selector != "":
+			if htmlDoc == nil {
+				htmlDoc, err = parseHTML(body)
+				if err != nil {
+					return err
+				}
+			}
+			extracted, err = extractSelector(
+				varName, entry.Selector, entry.Attr, htmlDoc,
+			)
+		case entry.From != "":
+			if !decoded {
+				bodyDoc, err = decodeBody(format, body)
+				if err != nil {
+					return VarFromBodyNotDecoded(varName, format, err)
+				}
+				decoded = true
+			}
+			extracted, err = extractFrom(varName, entry.From, entry.All, bodyDoc)
+		}
 		if err != nil {
 			return err
 		}
@@ -54,26 +148,241 @@ func saveVars(
 	return nil
 }
 
-func extractFrom(path string, out any) (any, error) {
-	var normalized any
-	switch out := out.(type) {
-	case map[string]any:
-		normalized = out
-	case []map[string]any:
-		normalized = out
-	default:
-		return nil, fmt.Errorf("unhandled extract type %T", out)
+// milliseconds returns the supplied duration as a number of milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// extractHeader returns the first value of the named HTTP header in the
+// supplied response.
+func extractHeader(
+	varName string,
+	header string,
+	resp *nethttp.Response,
+) (any, error) {
+	vals := resp.Header.Values(header)
+	if len(vals) == 0 {
+		return nil, VarHeaderNotFound(varName, header)
+	}
+	return vals[0], nil
+}
+
+// extractCookie returns the value of the named cookie set by the supplied
+// response.
+func extractCookie(
+	varName string,
+	cookie string,
+	resp *nethttp.Response,
+) (any, error) {
+	for _, c := range resp.Cookies() {
+		if c.Name == cookie {
+			return c.Value, nil
+		}
+	}
+	return nil, VarCookieNotFound(varName, cookie)
+}
+
+// extractRegex returns the text matched by the identified capture group of
+// the supplied regular expression in the raw response body.
+func extractRegex(
+	varName string,
+	expr string,
+	group string,
+	body []byte,
+) (any, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		// Not terminal because during parse we validate the regular
+		// expression is valid.
+		return nil, err
+	}
+	matches := re.FindSubmatch(body)
+	if matches == nil {
+		return nil, VarRegexNotMatched(varName, expr)
+	}
+	idx := regexGroupIndex(re, group)
+	if idx < 0 || idx >= len(matches) {
+		return nil, VarRegexNotMatched(varName, expr)
+	}
+	return string(matches[idx]), nil
+}
+
+// extractXPath returns the value of the supplied XPath expression evaluated
+// against the supplied XML document.
+func extractXPath(
+	varName string,
+	expr string,
+	doc *xmlquery.Node,
+) (any, error) {
+	compiled, err := xpath.Compile(expr)
+	if err != nil {
+		// Not terminal because during parse we validate the XPath
+		// expression is valid.
+		return nil, err
+	}
+	val, found := evalXPath(doc, compiled)
+	if !found {
+		return nil, VarXPathNotFound(varName, expr)
+	}
+	return val, nil
+}
+
+// extractSelector returns the text of the first element selected by the
+// supplied CSS selector in the supplied HTML document, or the value of the
+// element's named attribute if attr is not empty.
+func extractSelector(
+	varName string,
+	selector string,
+	attr string,
+	doc *goquery.Document,
+) (any, error) {
+	sel, err := cascadia.Compile(selector)
+	if err != nil {
+		// Not terminal because during parse we validate the CSS selector is
+		// valid.
+		return nil, err
+	}
+	val, found := selectHTML(doc, sel, attr)
+	if !found {
+		return nil, VarSelectorNotFound(varName, selector, attr)
+	}
+	return val, nil
+}
+
+// regexGroupIndex returns the index of the capture group in the supplied
+// regular expression identified by the supplied group index or name, or -1 if
+// there is no such capture group. An empty group identifies the first capture
+// group, or the entire match if there are no capture groups.
+func regexGroupIndex(re *regexp.Regexp, group string) int {
+	if group == "" {
+		return min(1, re.NumSubexp())
 	}
+	if idx, err := strconv.Atoi(group); err == nil {
+		if idx > re.NumSubexp() {
+			return -1
+		}
+		return idx
+	}
+	return re.SubexpIndex(group)
+}
+
+// extractFrom returns the value matched by the supplied JSONPath expression in
+// the supplied decoded JSON document, which may have any type of root
+// element. If all is true, all matched values are returned as a list.
+func extractFrom(
+	varName string,
+	path string,
+	all bool,
+	doc any,
+) (any, error) {
 	p, err := jsonpath.Parse(path)
 	if err != nil {
 		// Not terminal because during parse we validate the JSONPath
 		// expression is valid.
 		return nil, gdtjson.JSONPathNotFound(path, err)
 	}
-	nodes := p.Select(normalized)
+	nodes := p.Select(doc)
+	if all {
+		return []any(nodes), nil
+	}
 	if len(nodes) == 0 {
-		return nil, gdtjson.JSONPathNotFound(path, err)
+		return nil, api.JSONPathVarFromNotMatched(varName, path)
+	}
+	return nodes[0], nil
+}
+
+// lookupVar returns the value of the named variable. Variables saved by prior
+// test specs in the scenario are looked up first. If no such variable was
+// saved, the fixture registry is queried for a fixture with a state key
+// matching the name.
+func lookupVar(ctx context.Context, name string) (any, bool) {
+	if val, ok := gdtcontext.Run(ctx)[name]; ok {
+		return val, true
+	}
+	return fixtureState(ctx, name)
+}
+
+// fixtureState returns the state of the first fixture having a non-nil state
+// for the supplied key.
+func fixtureState(ctx context.Context, key string) (any, bool) {
+	fixtures := gdtcontext.Fixtures(ctx)
+	for _, f := range fixtures {
+		if !f.HasState(key) {
+			continue
+		}
+		if val := f.State(key); val != nil {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+// interpolate returns the supplied string with all `$(name)` variable
+// references replaced with the string form of the named variable's value.
+func interpolate(ctx context.Context, s string) (string, error) {
+	var err error
+	res := varRefRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		name := varRefRegex.FindStringSubmatch(ref)[1]
+		val, ok := lookupVar(ctx, name)
+		if !ok {
+			if err == nil {
+				err = VariableNotFound(name)
+			}
+			return ref
+		}
+		return varString(val)
+	})
+	if err != nil {
+		return "", err
+	}
+	return res, nil
+}
+
+// expandValue expands a string value from the test spec. If the entire string
+// is a key to some fixture state, for instance a JSONPath expression
+// evaluated by a JSON fixture, the fixture state is returned. If the entire
+// string is a single `$(name)` variable reference, the named variable's value
+// is returned with its type intact. Otherwise, all variable references in the
+// string are interpolated.
+func expandValue(ctx context.Context, s string) (any, error) {
+	if val, ok := fixtureState(ctx, s); ok {
+		return val, nil
+	}
+	if loc := varRefRegex.FindStringSubmatchIndex(s); loc != nil &&
+		loc[0] == 0 && loc[1] == len(s) {
+		name := s[loc[2]:loc[3]]
+		val, ok := lookupVar(ctx, name)
+		if !ok {
+			return nil, VariableNotFound(name)
+		}
+		return val, nil
+	}
+	return interpolate(ctx, s)
+}
+
+// expandString is like expandValue but always returns a string.
+func expandString(ctx context.Context, s string) (string, error) {
+	val, err := expandValue(ctx, s)
+	if err != nil {
+		return "", err
+	}
+	return varString(val), nil
+}
+
+// varString returns the string form of a variable's value. Values decoded from
+// JSON are float64 for all numbers, so integral floats are formatted without
+// an exponent or decimal point. Lists and maps are formatted as JSON.
+func varString(val any) string {
+	switch val := val.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case []any, map[string]any:
+		b, err := json.Marshal(val)
+		if err == nil {
+			return string(b)
+		}
 	}
-	got := nodes[0]
-	return got, nil
+	return fmt.Sprintf("%v", val)
 }
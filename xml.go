@@ -0,0 +1,139 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+package http
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+)
+
+// XMLExpect contains assertions about XML content in an HTTP response.
+type XMLExpect struct {
+	// Paths is a map, keyed by XPath expression, of the expected string
+	// value of the first node selected by the XPath expression. When the
+	// XPath expression evaluates to a number, string or boolean, for
+	// instance `count(//book)`, the expected value is compared with the
+	// string form of the result.
+	Paths map[string]string `yaml:"paths,omitempty"`
+	// Exists is a list of XPath expressions that should each select at least
+	// one node.
+	Exists []string `yaml:"exists,omitempty"`
+	// Count is a map, keyed by XPath expression, of the number of nodes the
+	// XPath expression should select.
+	Count map[string]int `yaml:"count,omitempty"`
+	// Schema is the path to an XSD document the XML content should validate
+	// against. Relative paths are resolved relative to the test scenario
+	// file.
+	Schema string `yaml:"schema,omitempty"`
+	// exprs contains the compiled XPath expressions, keyed by XPath
+	// expression.
+	exprs map[string]*xpath.Expr
+}
+
+// check returns nil if the supplied response content satisfies the
+// assertions, otherwise it returns the first failure.
+func (e *XMLExpect) check(b []byte) error {
+	doc, err := xmlquery.Parse(bytes.NewReader(b))
+	if err != nil {
+		return HTTPBodyNotXML(err)
+	}
+	if e.Schema != "" {
+		if err := validateXSD(e.Schema, b); err != nil {
+			return HTTPXMLSchemaInvalid(e.Schema, err)
+		}
+	}
+	for _, expr := range e.Exists {
+		if len(selectXPath(doc, e.exprs[expr])) == 0 {
+			return HTTPXPathNotFound(expr)
+		}
+	}
+	for expr, exp := range e.Count {
+		got := len(selectXPath(doc, e.exprs[expr]))
+		if got != exp {
+			return HTTPXPathCountNotEqual(expr, exp, got)
+		}
+	}
+	for expr, exp := range e.Paths {
+		val, found := evalXPath(doc, e.exprs[expr])
+		if !found {
+			return HTTPXPathNotFound(expr)
+		}
+		if got := xpathString(val); got != exp {
+			return HTTPXPathNotEqual(expr, exp, got)
+		}
+	}
+	return nil
+}
+
+// compile compiles and stores the supplied XPath expression, returning any
+// error compiling it.
+func (e *XMLExpect) compile(expr string) error {
+	if _, ok := e.exprs[expr]; ok {
+		return nil
+	}
+	compiled, err := xpath.Compile(expr)
+	if err != nil {
+		return err
+	}
+	if e.exprs == nil {
+		e.exprs = map[string]*xpath.Expr{}
+	}
+	e.exprs[expr] = compiled
+	return nil
+}
+
+// selectXPath returns the nodes in the supplied XML document selected by the
+// supplied XPath expression. An XPath expression that evaluates to true
+// rather than to a set of nodes selects the document itself.
+func selectXPath(doc *xmlquery.Node, expr *xpath.Expr) []*xmlquery.Node {
+	switch res := expr.Evaluate(xmlquery.CreateXPathNavigator(doc)).(type) {
+	case *xpath.NodeIterator:
+		nodes := []*xmlquery.Node{}
+		for res.MoveNext() {
+			nav := res.Current().(*xmlquery.NodeNavigator)
+			nodes = append(nodes, nav.Current())
+		}
+		return nodes
+	case bool:
+		if res {
+			return []*xmlquery.Node{doc}
+		}
+	}
+	return nil
+}
+
+// evalXPath returns the value of the supplied XPath expression evaluated
+// against the supplied XML document, along with whether the XPath expression
+// selected anything. The value is the text of the first selected node, or the
+// result of an XPath expression that evaluates to a number, string or
+// boolean.
+func evalXPath(doc *xmlquery.Node, expr *xpath.Expr) (any, bool) {
+	switch res := expr.Evaluate(xmlquery.CreateXPathNavigator(doc)).(type) {
+	case *xpath.NodeIterator:
+		if !res.MoveNext() {
+			return nil, false
+		}
+		return res.Current().Value(), true
+	case float64, string, bool:
+		return res, true
+	}
+	return nil, false
+}
+
+// xpathString returns the string form of a value returned by evalXPath.
+func xpathString(v any) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	}
+	return ""
+}
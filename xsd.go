@@ -0,0 +1,33 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+//go:build xsd
+
+package http
+
+import (
+	"sync"
+
+	xsdvalidate "github.com/terminalstatic/go-xsd-validate"
+)
+
+// xsdSupported is true when gdt-http is built with the `xsd` build tag, which
+// validates XML content against XSD documents using libxml2.
+const xsdSupported = true
+
+var xsdInit = sync.OnceValue(xsdvalidate.Init)
+
+// validateXSD validates the supplied XML content against the XSD document at
+// the supplied path.
+func validateXSD(path string, b []byte) error {
+	if err := xsdInit(); err != nil {
+		return err
+	}
+	h, err := xsdvalidate.NewXsdHandlerUrl(path, xsdvalidate.ParsErrDefault)
+	if err != nil {
+		return err
+	}
+	defer h.Free()
+	return h.ValidateMem(b, xsdvalidate.ValidErrDefault)
+}
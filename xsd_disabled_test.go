@@ -0,0 +1,32 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+//go:build !xsd
+
+package http_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gdt-dev/core/scenario"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXMLSchemaNotSupported(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "xml-schema.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.NotNil(err)
+	assert.ErrorContains(err, "XSD validation requires building with the `xsd` build tag")
+	require.Nil(s)
+}
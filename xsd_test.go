@@ -0,0 +1,59 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+
+//go:build xsd
+
+package http_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gdt-dev/core/api"
+	gdtcontext "github.com/gdt-dev/core/context"
+	"github.com/gdt-dev/core/scenario"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXMLSchema(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "xml-schema.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	err = s.Run(ctx, t)
+	require.Nil(err)
+}
+
+func TestXMLSchemaFailure(t *testing.T) {
+	require := require.New(t)
+
+	fp := filepath.Join("testdata", "xml-schema-failure.yaml")
+	f, err := os.Open(fp)
+	require.Nil(err)
+	defer f.Close() // nolint:errcheck
+
+	s, err := scenario.FromReader(f, scenario.WithPath(fp))
+	require.Nil(err)
+	require.NotNil(s)
+
+	ctx := gdtcontext.New()
+	ctx = setup(ctx)
+
+	failures := evalFailures(ctx, t, s)
+	require.Len(failures, 1)
+	require.ErrorIs(failures[0], api.ErrFailure)
+	require.ErrorContains(failures[0], "XML content did not adhere to XSD")
+	require.ErrorContains(failures[0], "Expected is ( isbn )")
+}